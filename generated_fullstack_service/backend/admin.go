@@ -0,0 +1,54 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	adminResetPath        = "/v1/admin/reset"
+	adminConfigPath       = "/v1/admin/config"
+	adminConfigReloadPath = "/v1/admin/config/reload"
+	adminReadOnlyPath     = "/v1/admin/read-only"
+	adminTokenHeader      = "X-Admin-Token"
+)
+
+// adminTokenPaths are the routes behind AdminToken.
+var adminTokenPaths = map[string]bool{adminResetPath: true, adminConfigPath: true, adminConfigReloadPath: true, adminReadOnlyPath: true}
+
+// AdminToken answers requests that don't send token in X-Admin-Token
+// exactly as an unknown route, so the route it guards can't be discovered.
+func AdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader(adminTokenHeader)), []byte(token)) != 1 {
+			respondError(c, 404, CodeRouteNotFound, "no route for "+c.Request.Method+" "+c.Request.URL.Path)
+			return
+		}
+		c.Next()
+	}
+}
+
+// registerReset serves POST /v1/admin/reset behind AdminToken. It is meant
+// for test and demo instances, so it is only registered when ADMIN_TOKEN is
+// set.
+func (h *TaskHandler) registerReset(r gin.IRouter, token string) {
+	r.POST(adminResetPath, AdminToken(token), h.Reset)
+}
+
+// Reset deletes every task, trashed ones included, for good, and forgets the
+// idempotency keys of the creates that made them. The deletes are audited
+// and broadcast like any other.
+func (h *TaskHandler) Reset(c *gin.Context) {
+	ctx := c.Request.Context()
+	removed, err := h.as(ctx, "").ReplaceAll(ctx, nil)
+	if err != nil {
+		respondModifyError(c, err)
+		return
+	}
+	h.idempotency.Clear()
+	tasksGauge.Set(0)
+	slog.Warn("store reset", "removed", len(removed), "request_id", c.GetString(requestIDKey))
+	c.JSON(200, gin.H{"removed": len(removed)})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// defaultArchiveInterval is how often the archiver runs unless
+// ARCHIVE_INTERVAL says otherwise.
+const defaultArchiveInterval = time.Hour
+
+// shouldArchive reports whether the archiver takes t: an active, done task
+// not updated since cutoff.
+func shouldArchive(t Task, cutoff time.Time) bool {
+	return t.DeletedAt == nil && t.Done && !t.Archived && t.UpdatedAt.Before(cutoff)
+}
+
+// archiveStale archives every task shouldArchive takes, as of now and after,
+// in one atomic change, and returns how many it archived. Archiving is an
+// update like any other, audited and broadcast, with no subject.
+func (h *TaskHandler) archiveStale(ctx context.Context, now time.Time, after time.Duration) (int, error) {
+	tasks, err := h.repo.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := now.Add(-after)
+	var ids []string
+	for _, t := range tasks {
+		if shouldArchive(t, cutoff) {
+			ids = append(ids, t.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	// A task changed since it was listed is checked again.
+	archived, _, err := h.as(ctx, "").ModifyMany(ctx, ids, func(t *Task) error {
+		if !shouldArchive(*t, cutoff) {
+			return errSkip
+		}
+		t.Archived = true
+		t.UpdatedAt = now
+		t.Version++
+		return nil
+	})
+	return len(archived), err
+}
+
+// RunArchiver archives done tasks not updated for after, every interval,
+// until ctx is cancelled. Each run is logged with the number it archived.
+func (h *TaskHandler) RunArchiver(ctx context.Context, after, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			n, err := h.archiveStale(ctx, now.UTC(), after)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("auto-archive failed", "error", err, "archived", n)
+				}
+				continue
+			}
+			slog.Info("auto-archive finished", "archived", n)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Audit actions. Trashing and restoring are recorded separately from other
+// updates, and delete means the task was removed for good.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditTrash   = "trash"
+	AuditRestore = "restore"
+	AuditDelete  = "delete"
+)
+
+// AuditEntry records one change to one task.
+type AuditEntry struct {
+	ID int64     `json:"id"`
+	At time.Time `json:"at"`
+	// Subject is the authenticated caller, empty when auth is disabled.
+	Subject string                 `json:"subject,omitempty"`
+	Action  string                 `json:"action"`
+	TaskID  string                 `json:"task_id"`
+	Changes map[string]FieldChange `json:"changes"`
+	// Undoes is the ID of the entry this change reversed through POST
+	// /tasks/undo, 0 for any other change.
+	Undoes int64 `json:"undoes,omitempty"`
+}
+
+// FieldChange is a task field's value before and after a change, as JSON
+// values. From is null on create and To is null on delete.
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// AuditLog is an append-only record of task changes, implemented by every
+// storage backend alongside TaskRepository.
+type AuditLog interface {
+	// AppendAudit stores e, assigning its ID.
+	AppendAudit(e AuditEntry) error
+	// ListAudit returns entries oldest first, restricted to taskID unless
+	// it is empty, along with the total number of matching entries.
+	ListAudit(taskID string, limit, offset int) ([]AuditEntry, int, error)
+	// LastUndoable returns subject's most recent entry that neither undoes
+	// another nor has been undone itself.
+	LastUndoable(subject string) (AuditEntry, bool, error)
+}
+
+// Store is a complete storage backend.
+type Store interface {
+	TaskRepository
+	AuditLog
+}
+
+// auditIgnoredFields change on every write and would only add noise to the
+// recorded diffs.
+var auditIgnoredFields = map[string]bool{"id": true, "updated_at": true, "version": true}
+
+// diffTasks returns the fields that differ between before and after, either
+// of which may be nil.
+func diffTasks(before, after *Task) map[string]FieldChange {
+	from, to := taskFields(before), taskFields(after)
+	changes := map[string]FieldChange{}
+	for name, v := range to {
+		if !auditIgnoredFields[name] && !reflect.DeepEqual(from[name], v) {
+			changes[name] = FieldChange{From: from[name], To: v}
+		}
+	}
+	for name, v := range from {
+		if _, ok := to[name]; !ok && v != nil && !auditIgnoredFields[name] {
+			changes[name] = FieldChange{From: v}
+		}
+	}
+	return changes
+}
+
+// taskFields returns t as it is serialised in API responses.
+func taskFields(t *Task) map[string]any {
+	fields := map[string]any{}
+	if t == nil {
+		return fields
+	}
+	b, _ := json.Marshal(t)
+	json.Unmarshal(b, &fields)
+	return fields
+}
+
+// auditingStore is a TaskRepository decorator that appends an AuditEntry,
+// attributed to subject, for every change made through it.
+type auditingStore struct {
+	TaskRepository
+	log     AuditLog
+	subject string
+	// undoes is set on the entries recorded, see AuditEntry.Undoes.
+	undoes int64
+}
+
+func (s *auditingStore) record(action, taskID string, before, after *Task) {
+	e := AuditEntry{
+		At:      time.Now().UTC(),
+		Subject: s.subject,
+		Action:  action,
+		TaskID:  taskID,
+		Changes: diffTasks(before, after),
+		Undoes:  s.undoes,
+	}
+	// The change has already been committed, so a failure here can only be
+	// reported, not undone.
+	if err := s.log.AppendAudit(e); err != nil {
+		log.Printf("audit: record %s of task %s: %v", action, taskID, err)
+	}
+}
+
+func (s *auditingStore) Create(ctx context.Context, t Task) error {
+	if err := s.TaskRepository.Create(ctx, t); err != nil {
+		return err
+	}
+	s.record(AuditCreate, t.ID, nil, &t)
+	return nil
+}
+
+func (s *auditingStore) CreateMany(ctx context.Context, ts []Task) error {
+	if err := s.TaskRepository.CreateMany(ctx, ts); err != nil {
+		return err
+	}
+	for i := range ts {
+		s.record(AuditCreate, ts[i].ID, nil, &ts[i])
+	}
+	return nil
+}
+
+func (s *auditingStore) Update(ctx context.Context, id string, t Task) (bool, error) {
+	before, _, err := s.TaskRepository.Get(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	found, err := s.TaskRepository.Update(ctx, id, t)
+	if err == nil && found {
+		s.record(AuditUpdate, id, &before, &t)
+	}
+	return found, err
+}
+
+func (s *auditingStore) Modify(ctx context.Context, id string, fn func(*Task) error) (Task, bool, error) {
+	var before Task
+	t, found, err := s.TaskRepository.Modify(ctx, id, func(t *Task) error {
+		before = *t
+		return fn(t)
+	})
+	if err != nil || !found {
+		return t, found, err
+	}
+	s.record(auditAction(before, t), id, &before, &t)
+	return t, true, nil
+}
+
+// auditAction classifies a change by the trash state before and after it.
+func auditAction(before, after Task) string {
+	switch {
+	case after.DeletedAt != nil && before.DeletedAt == nil:
+		return AuditTrash
+	case after.DeletedAt == nil && before.DeletedAt != nil:
+		return AuditRestore
+	}
+	return AuditUpdate
+}
+
+func (s *auditingStore) ModifyMany(ctx context.Context, ids []string, fn func(*Task) error) ([]Task, []string, error) {
+	before := map[string]Task{}
+	modified, missing, err := s.TaskRepository.ModifyMany(ctx, ids, func(t *Task) error {
+		before[t.ID] = *t
+		return fn(t)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range modified {
+		b := before[modified[i].ID]
+		s.record(auditAction(b, modified[i]), modified[i].ID, &b, &modified[i])
+	}
+	return modified, missing, nil
+}
+
+func (s *auditingStore) Delete(ctx context.Context, id string) (bool, error) {
+	before, _, err := s.TaskRepository.Get(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	found, err := s.TaskRepository.Delete(ctx, id)
+	if err == nil && found {
+		s.record(AuditDelete, id, &before, nil)
+	}
+	return found, err
+}
+
+func (s *auditingStore) TrashCompleted(ctx context.Context, at time.Time) ([]Task, error) {
+	trashed, err := s.TaskRepository.TrashCompleted(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	for i := range trashed {
+		// Only the ignored bookkeeping fields and deleted_at changed.
+		before := trashed[i]
+		before.DeletedAt = nil
+		s.record(AuditTrash, before.ID, &before, &trashed[i])
+	}
+	return trashed, nil
+}
+
+// ReplaceAll records a delete for every task removed and a create for every
+// one put in their place.
+func (s *auditingStore) ReplaceAll(ctx context.Context, ts []Task) ([]Task, error) {
+	removed, err := s.TaskRepository.ReplaceAll(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range removed {
+		s.record(AuditDelete, removed[i].ID, &removed[i], nil)
+	}
+	for i := range ts {
+		s.record(AuditCreate, ts[i].ID, nil, &ts[i])
+	}
+	return removed, nil
+}
+
+// as returns the repository to make changes through on behalf of subject,
+// so they are traced and audited, ownership is enforced and completing a
+// recurring task schedules the next occurrence. Each call still takes its
+// own context; ctx is only consulted for isDryRun.
+func (h *TaskHandler) as(ctx context.Context, subject string) TaskRepository {
+	return h.undoing(ctx, subject, 0)
+}
+
+// undoing is as, with the changes recorded as undoing the audit entry with
+// ID undoes. On a context marked by withDryRun the changes are only checked.
+func (h *TaskHandler) undoing(ctx context.Context, subject string, undoes int64) TaskRepository {
+	var repo TaskRepository = &auditingStore{TaskRepository: h.repo, log: h.audit, subject: subject, undoes: undoes}
+	if isDryRun(ctx) {
+		repo = &dryRunStore{h.repo}
+	}
+	if subject != "" {
+		repo = &ownerGuard{TaskRepository: repo, subject: subject, ownerOnly: h.ownerOnlyWrites}
+	}
+	repo = &recurringStore{repo}
+	// Outside recurringStore: the next occurrence of a completed task is
+	// not a new task as far as the limit goes.
+	if h.maxTasks > 0 {
+		repo = &limitedStore{TaskRepository: repo, max: h.maxTasks, mu: &h.createMu}
+	}
+	return repo
+}
+
+// asCaller is as for the subject and context of the current request.
+func (h *TaskHandler) asCaller(c *gin.Context) TaskRepository {
+	return h.as(c.Request.Context(), c.GetString(subjectKey))
+}
+
+// ListAudit serves the audit log, optionally filtered with ?task_id=.
+func (h *TaskHandler) ListAudit(c *gin.Context) {
+	limit, offset, err := parsePagination(c, h.pageLimit)
+	if err != nil {
+		respondError(c, 400, CodeInvalidRequest, err.Error())
+		return
+	}
+	entries, total, err := h.audit.ListAudit(c.Query("task_id"), limit, offset)
+	if err != nil {
+		respondError(c, 500, CodeInternal, err.Error())
+		return
+	}
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.Header("X-Limit", strconv.Itoa(limit))
+	c.Header("X-Offset", strconv.Itoa(offset))
+	c.JSON(200, entries)
+}
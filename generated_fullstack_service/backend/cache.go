@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CachingStore is a Store decorator that serves List and Get from memory
+// for up to ttl after reading them from the underlying store. Any write
+// through it drops everything cached, so it must wrap the only path to the
+// store: writes made elsewhere, such as by another server instance sharing
+// a database, can go unseen for up to ttl.
+type CachingStore struct {
+	Store
+	ttl time.Duration
+
+	mu sync.Mutex
+	// gen counts invalidations. A read only fills the cache if no write
+	// happened while it was in flight, so it can't cache stale data.
+	gen       uint64
+	list      []Task
+	listUntil time.Time
+	tasks     map[string]cachedTask
+}
+
+type cachedTask struct {
+	task  Task
+	found bool
+	until time.Time
+}
+
+func NewCachingStore(s Store, ttl time.Duration) *CachingStore {
+	return &CachingStore{Store: s, ttl: ttl, tasks: map[string]cachedTask{}}
+}
+
+// List returns a copy of the cached list, refreshing it once it expires.
+func (s *CachingStore) List(ctx context.Context) ([]Task, error) {
+	s.mu.Lock()
+	if s.list != nil && time.Now().Before(s.listUntil) {
+		out := append([]Task(nil), s.list...)
+		s.mu.Unlock()
+		cacheHits.WithLabelValues("list").Inc()
+		return out, nil
+	}
+	gen := s.gen
+	s.mu.Unlock()
+	cacheMisses.WithLabelValues("list").Inc()
+
+	tasks, err := s.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if s.gen == gen {
+		s.list = append([]Task(nil), tasks...)
+		s.listUntil = time.Now().Add(s.ttl)
+	}
+	s.mu.Unlock()
+	return tasks, nil
+}
+
+// Get caches misses as well as hits, so polling for a deleted task doesn't
+// reach the store either.
+func (s *CachingStore) Get(ctx context.Context, id string) (Task, bool, error) {
+	s.mu.Lock()
+	if e, ok := s.tasks[id]; ok && time.Now().Before(e.until) {
+		s.mu.Unlock()
+		cacheHits.WithLabelValues("get").Inc()
+		return e.task, e.found, nil
+	}
+	gen := s.gen
+	s.mu.Unlock()
+	cacheMisses.WithLabelValues("get").Inc()
+
+	t, found, err := s.Store.Get(ctx, id)
+	if err != nil {
+		return Task{}, false, err
+	}
+	s.mu.Lock()
+	if s.gen == gen {
+		s.tasks[id] = cachedTask{task: t, found: found, until: time.Now().Add(s.ttl)}
+	}
+	s.mu.Unlock()
+	return t, found, nil
+}
+
+// invalidate drops everything cached. Writes call it both before and after
+// reaching the store: the first makes in-flight reads discard what they
+// fetched, the second drops anything cached in between.
+func (s *CachingStore) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gen++
+	s.list = nil
+	clear(s.tasks)
+}
+
+func (s *CachingStore) Create(ctx context.Context, t Task) error {
+	s.invalidate()
+	defer s.invalidate()
+	return s.Store.Create(ctx, t)
+}
+
+func (s *CachingStore) CreateMany(ctx context.Context, ts []Task) error {
+	s.invalidate()
+	defer s.invalidate()
+	return s.Store.CreateMany(ctx, ts)
+}
+
+func (s *CachingStore) Update(ctx context.Context, id string, t Task) (bool, error) {
+	s.invalidate()
+	defer s.invalidate()
+	return s.Store.Update(ctx, id, t)
+}
+
+func (s *CachingStore) Modify(ctx context.Context, id string, fn func(*Task) error) (Task, bool, error) {
+	s.invalidate()
+	defer s.invalidate()
+	return s.Store.Modify(ctx, id, fn)
+}
+
+func (s *CachingStore) ModifyMany(ctx context.Context, ids []string, fn func(*Task) error) ([]Task, []string, error) {
+	s.invalidate()
+	defer s.invalidate()
+	return s.Store.ModifyMany(ctx, ids, fn)
+}
+
+func (s *CachingStore) Delete(ctx context.Context, id string) (bool, error) {
+	s.invalidate()
+	defer s.invalidate()
+	return s.Store.Delete(ctx, id)
+}
+
+func (s *CachingStore) TrashCompleted(ctx context.Context, at time.Time) ([]Task, error) {
+	s.invalidate()
+	defer s.invalidate()
+	return s.Store.TrashCompleted(ctx, at)
+}
+
+func (s *CachingStore) ReplaceAll(ctx context.Context, ts []Task) ([]Task, error) {
+	s.invalidate()
+	defer s.invalidate()
+	return s.Store.ReplaceAll(ctx, ts)
+}
@@ -0,0 +1,191 @@
+// Package client is a Go client for the task service's /v1 API.
+//
+//	c := client.New("http://localhost:8080", token)
+//	task, err := c.CreateTask(ctx, client.Task{Title: "Write docs"})
+//	if errors.Is(err, client.ErrInvalid) {
+//		// the server rejected the task; err is an *Error with the details
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client calls the API at BaseURL. Its zero value is not usable; create one
+// with New. A Client is safe for concurrent use.
+type Client struct {
+	// BaseURL is the server's root, such as http://localhost:8080; the /v1
+	// prefix is added by the client.
+	BaseURL string
+	// Token, when set, is sent as a bearer token with every request.
+	Token string
+	// HTTPClient makes the requests; nil means http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// New returns a Client for the server at baseURL, sending token as a bearer
+// token unless it is empty.
+func New(baseURL, token string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), Token: token}
+}
+
+// ListOptions narrows and pages ListTasks. Zero values are left out of the
+// request, so the server's defaults apply.
+type ListOptions struct {
+	// Query matches a case-insensitive substring of the title.
+	Query string
+	Done  *bool
+	// Priorities and Owners accept a task with any of the values; Tags
+	// require every tag.
+	Priorities []string
+	Owners     []string
+	Tags       []string
+	Overdue    *bool
+	// Archived lists archived tasks instead of the others.
+	Archived bool
+	// Sort is a field name, prefixed with - for descending.
+	Sort   string
+	Limit  int
+	Offset int
+}
+
+func (o ListOptions) values() url.Values {
+	v := url.Values{}
+	if o.Query != "" {
+		v.Set("q", o.Query)
+	}
+	if o.Done != nil {
+		v.Set("done", strconv.FormatBool(*o.Done))
+	}
+	v["priority"] = o.Priorities
+	v["owner"] = o.Owners
+	v["tag"] = o.Tags
+	if o.Overdue != nil {
+		v.Set("overdue", strconv.FormatBool(*o.Overdue))
+	}
+	if o.Archived {
+		v.Set("archived", "true")
+	}
+	if o.Sort != "" {
+		v.Set("sort", o.Sort)
+	}
+	if o.Limit > 0 {
+		v.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		v.Set("offset", strconv.Itoa(o.Offset))
+	}
+	return v
+}
+
+// TaskList is one page of ListTasks. Total counts every matching task, not
+// just those on the page.
+type TaskList struct {
+	Tasks []Task
+	Total int
+}
+
+// ListTasks returns the page of tasks opts selects.
+func (c *Client) ListTasks(ctx context.Context, opts ListOptions) (TaskList, error) {
+	var list TaskList
+	resp, err := c.do(ctx, http.MethodGet, "/v1/tasks?"+opts.values().Encode(), nil, &list.Tasks)
+	if err != nil {
+		return TaskList{}, err
+	}
+	list.Total, _ = strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	if list.Tasks == nil {
+		list.Tasks = []Task{}
+	}
+	return list, nil
+}
+
+// GetTask returns the task with the given id, or an error matching
+// ErrNotFound if there is none, or it is in the trash.
+func (c *Client) GetTask(ctx context.Context, id string) (Task, error) {
+	var task Task
+	_, err := c.do(ctx, http.MethodGet, taskPath(id), nil, &task)
+	return task, err
+}
+
+// CreateTask creates task and returns it as stored, with its id and
+// timestamps filled in by the server.
+func (c *Client) CreateTask(ctx context.Context, task Task) (Task, error) {
+	var created Task
+	_, err := c.do(ctx, http.MethodPost, "/v1/tasks", task, &created)
+	return created, err
+}
+
+// UpdateTask replaces the task with task.ID by task and returns the result.
+// A nonzero task.Version must be the stored one, or the error matches
+// ErrConflict, so a task that was read, changed and sent back can't
+// overwrite someone else's update.
+func (c *Client) UpdateTask(ctx context.Context, task Task) (Task, error) {
+	if task.ID == "" {
+		return Task{}, errors.New("client: UpdateTask needs a task id")
+	}
+	var updated Task
+	_, err := c.do(ctx, http.MethodPut, taskPath(task.ID), task, &updated)
+	return updated, err
+}
+
+// DeleteTask moves the task with the given id to the trash, from which it
+// can still be restored.
+func (c *Client) DeleteTask(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, taskPath(id), nil, nil)
+	return err
+}
+
+func taskPath(id string) string {
+	return "/v1/tasks/" + url.PathEscape(id)
+}
+
+// do sends body, if not nil, as JSON to path and decodes a successful
+// response into out, if not nil. Responses outside 2xx become an *Error.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, newError(resp)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp, fmt.Errorf("client: decode %s %s response: %w", method, path, err)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Errors an *Error matches with errors.Is, by response status.
+var (
+	// ErrInvalid is a 400 or 422: the request or the task in it was
+	// rejected.
+	ErrInvalid = errors.New("invalid request")
+	// ErrUnauthorized is a 401 or 403: the token is missing, invalid or
+	// not allowed to make the change.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrNotFound is a 404.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is a 409 or 412, such as an update of an outdated
+	// version.
+	ErrConflict = errors.New("conflict")
+	// ErrTooLarge is a 413: the request body is over the server's limit.
+	ErrTooLarge = errors.New("request too large")
+	// ErrUnsupportedMedia is a 415: the body was sent without a JSON
+	// Content-Type.
+	ErrUnsupportedMedia = errors.New("unsupported media type")
+	// ErrRateLimited is a 429.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnavailable is a 503, such as a write while the server is read
+	// only.
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// Error is a response outside 2xx. Code, Message and RequestID come from the
+// server's {"error": ...} body when it sent one; Details holds its
+// code-specific context, such as the current version on a VERSION_CONFLICT.
+type Error struct {
+	StatusCode int
+	Code       string          `json:"code"`
+	Message    string          `json:"message"`
+	RequestID  string          `json:"request_id"`
+	Details    json.RawMessage `json:"details"`
+}
+
+func newError(resp *http.Response) *Error {
+	var body struct {
+		Error Error `json:"error"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	json.Unmarshal(b, &body)
+	e := body.Error
+	e.StatusCode = resp.StatusCode
+	if e.Message == "" {
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+	return &e
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("client: %d %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("client: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Is matches the Err* sentinel for e's status. Statuses without one, such as
+// a 500, match none and are only told apart by StatusCode.
+func (e *Error) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return target == ErrInvalid
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return target == ErrUnauthorized
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return target == ErrConflict
+	case http.StatusRequestEntityTooLarge:
+		return target == ErrTooLarge
+	case http.StatusUnsupportedMediaType:
+		return target == ErrUnsupportedMedia
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	case http.StatusServiceUnavailable:
+		return target == ErrUnavailable
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Task is a task as the API sends and accepts it. The server uses this same
+// type, so the two can't drift apart.
+type Task struct {
+	XMLName  xml.Name `json:"-" xml:"task"`
+	ID       string   `json:"id" xml:"id"`
+	Title    string   `json:"title" xml:"title" binding:"required,max=280"`
+	Done     bool     `json:"done" xml:"done"`
+	Priority string   `json:"priority" xml:"priority"`
+	// DueDate is optional; a nil value is serialized as null, or left out
+	// of XML.
+	DueDate   *time.Time `json:"due_date" xml:"due_date,omitempty"`
+	CreatedAt time.Time  `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" xml:"updated_at"`
+	// Tags are free-form labels; each is non-empty and appears once.
+	Tags []string `json:"tags" xml:"tags>tag"`
+	// Subtasks is an ordered checklist. Under the manual CompletionMode,
+	// completing subtasks does not complete the task; Done is set
+	// independently.
+	Subtasks []Subtask `json:"subtasks" xml:"subtasks>subtask"`
+	// CompletionMode is manual or auto. In auto mode the server keeps Done
+	// in step with Subtasks.
+	CompletionMode string `json:"completion_mode" xml:"completion_mode"`
+	// Comments are notes left on the task, oldest first. They are added
+	// and removed only through /tasks/:id/comments.
+	Comments []Comment `json:"comments" xml:"comments>comment"`
+	// Recurrence is none, daily, weekly or monthly.
+	Recurrence string `json:"recurrence" xml:"recurrence"`
+	// Owner is the token subject that created the task when auth is
+	// enabled, and whatever the client sent otherwise.
+	Owner string `json:"owner" xml:"owner"`
+	// ParentID is set on occurrences of a recurring task to the id of the
+	// first task in the series.
+	ParentID string `json:"parent_id,omitempty" xml:"parent_id,omitempty"`
+	// Order is the task's manual position, set through POST
+	// /tasks/:id/move. Tasks that were never placed have 0 and come after
+	// those that were.
+	Order int `json:"order" xml:"order"`
+	// Archived hides the task from the default list. The server's archiver
+	// sets it on done tasks left alone long enough; clients can set and
+	// clear it too.
+	Archived bool `json:"archived" xml:"archived"`
+	// DeletedAt is set while the task is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" xml:"deleted_at,omitempty"`
+	// Version starts at 1 and is incremented on every update. Clients send
+	// back the version they read to detect concurrent modifications.
+	Version int `json:"version" xml:"version"`
+}
+
+// Subtask is a checklist item within a task. Its ID is unique within the
+// task and assigned by the server.
+type Subtask struct {
+	ID    string `json:"id" xml:"id"`
+	Title string `json:"title" xml:"title" binding:"required,max=280"`
+	Done  bool   `json:"done" xml:"done"`
+}
+
+// Comment is a note on a task. Its ID is assigned by the server, and Author
+// is the token subject when auth is enabled.
+type Comment struct {
+	ID        string    `json:"id" xml:"id"`
+	Author    string    `json:"author" xml:"author"`
+	Body      string    `json:"body" xml:"body"`
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"task-service/client"
+)
+
+// newTestClient serves NewRouter with cfg and returns a client for it,
+// authenticated as alice if cfg has a JWT secret.
+func newTestClient(t *testing.T, cfg Config) *client.Client {
+	t.Helper()
+	srv := newTestServer(t, cfg)
+	token := ""
+	if cfg.JWTSecret != "" {
+		token = strings.TrimPrefix(testToken(t, cfg.JWTSecret, "alice"), "Bearer ")
+	}
+	c := client.New(srv.URL, token)
+	c.HTTPClient = srv.Client()
+	return c
+}
+
+func TestClientCRUD(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t, testConfig())
+
+	created, err := c.CreateTask(ctx, client.Task{Title: "Write docs", Tags: []string{"docs"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" || created.Version != 1 || created.Priority != PriorityMedium {
+		t.Fatalf("created %+v, want an id, version 1 and the default priority", created)
+	}
+	if _, err := c.CreateTask(ctx, client.Task{Title: "Review docs", Done: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.GetTask(ctx, created.ID)
+	if err != nil || got.Title != "Write docs" {
+		t.Fatalf("GetTask = %+v, %v", got, err)
+	}
+
+	done := false
+	list, err := c.ListTasks(ctx, client.ListOptions{Done: &done})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || len(list.Tasks) != 1 || list.Tasks[0].ID != created.ID {
+		t.Errorf("open tasks = %+v, want only %s", list, created.ID)
+	}
+	if list, err := c.ListTasks(ctx, client.ListOptions{Limit: 1, Offset: 1}); err != nil || list.Total != 2 || len(list.Tasks) != 1 {
+		t.Errorf("second page = %+v, %v; want one of two tasks", list, err)
+	}
+
+	got.Title = "Write better docs"
+	updated, err := c.UpdateTask(ctx, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Title != "Write better docs" || updated.Version != got.Version+1 {
+		t.Errorf("updated %+v, want the new title and version %d", updated, got.Version+1)
+	}
+
+	if err := c.DeleteTask(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetTask(ctx, created.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("GetTask after delete: %v, want ErrNotFound", err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	ctx := context.Background()
+	cfg := testConfig()
+	cfg.JWTSecret = "test-secret"
+	cfg.MaxBodyBytes = 1024
+	c := newTestClient(t, cfg)
+	task, err := c.CreateTask(ctx, client.Task{Title: "Target"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.CreateTask(ctx, client.Task{Title: "   "})
+	var apiErr *client.Error
+	if !errors.Is(err, client.ErrInvalid) || !errors.As(err, &apiErr) {
+		t.Fatalf("blank title: %v, want an *Error matching ErrInvalid", err)
+	}
+	if apiErr.StatusCode != 422 || apiErr.Code != CodeValidationFailed || !strings.Contains(string(apiErr.Details), "title") || apiErr.RequestID == "" {
+		t.Errorf("blank title: %+v, want 422 %s naming title, with a request id", apiErr, CodeValidationFailed)
+	}
+
+	stale := task
+	task.Title = "Moved on"
+	if _, err := c.UpdateTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	stale.Title = "Outdated"
+	if _, err := c.UpdateTask(ctx, stale); !errors.Is(err, client.ErrConflict) {
+		t.Errorf("stale update: %v, want ErrConflict", err)
+	}
+
+	if _, err := c.GetTask(ctx, "missing"); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("unknown task: %v, want ErrNotFound", err)
+	}
+	if _, err := c.CreateTask(ctx, client.Task{Title: "Big", Tags: []string{strings.Repeat("x", 2000)}}); !errors.Is(err, client.ErrTooLarge) {
+		t.Errorf("oversized body: %v, want ErrTooLarge", err)
+	}
+
+	anonymous := client.New(c.BaseURL, "")
+	anonymous.HTTPClient = c.HTTPClient
+	if _, err := anonymous.CreateTask(ctx, client.Task{Title: "Sneaky"}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("create without a token: %v, want ErrUnauthorized", err)
+	}
+	if _, err := c.UpdateTask(ctx, client.Task{Title: "No id"}); err == nil {
+		t.Error("UpdateTask without an id succeeded")
+	}
+}
+
+func TestClientErrorIs(t *testing.T) {
+	sentinels := []error{client.ErrInvalid, client.ErrUnauthorized, client.ErrNotFound, client.ErrConflict,
+		client.ErrTooLarge, client.ErrUnsupportedMedia, client.ErrRateLimited, client.ErrUnavailable}
+	for status, want := range map[int]error{
+		http.StatusBadRequest:            client.ErrInvalid,
+		http.StatusUnprocessableEntity:   client.ErrInvalid,
+		http.StatusUnauthorized:          client.ErrUnauthorized,
+		http.StatusForbidden:             client.ErrUnauthorized,
+		http.StatusNotFound:              client.ErrNotFound,
+		http.StatusConflict:              client.ErrConflict,
+		http.StatusPreconditionFailed:    client.ErrConflict,
+		http.StatusRequestEntityTooLarge: client.ErrTooLarge,
+		http.StatusUnsupportedMediaType:  client.ErrUnsupportedMedia,
+		http.StatusTooManyRequests:       client.ErrRateLimited,
+		http.StatusServiceUnavailable:    client.ErrUnavailable,
+		http.StatusInternalServerError:   nil,
+	} {
+		err := &client.Error{StatusCode: status}
+		for _, sentinel := range sentinels {
+			if got := errors.Is(err, sentinel); got != (sentinel == want) {
+				t.Errorf("errors.Is(%d, %v) = %v", status, sentinel, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import "time"
+
+// Event formats, selected by EVENT_FORMAT, for the change events sent to
+// webhooks, the SSE stream and WebSockets.
+const (
+	EventFormatRaw         = "raw"
+	EventFormatCloudEvents = "cloudevents"
+)
+
+const (
+	cloudEventsSpecVersion = "1.0"
+	// cloudEventTypePrefix is followed by the TaskEvent type, as in
+	// com.example.task.created.
+	cloudEventTypePrefix = "com.example.task."
+	// cloudEventSource is the source of every event; together with the
+	// event id it identifies an event.
+	cloudEventSource = "/v1/tasks"
+	// cloudEventsContentType is the Content-Type of a webhook delivery in
+	// the CloudEvents structured mode.
+	cloudEventsContentType = "application/cloudevents+json"
+)
+
+// CloudEvent is a TaskEvent in the CloudEvents 1.0 JSON format, with the
+// task as data and its id as subject.
+type CloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	Type            string    `json:"type"`
+	Source          string    `json:"source"`
+	ID              string    `json:"id"`
+	Time            time.Time `json:"time"`
+	Subject         string    `json:"subject"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            Task      `json:"data"`
+}
+
+func newCloudEvent(ev TaskEvent) CloudEvent {
+	return CloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		Type:            cloudEventTypePrefix + ev.Type,
+		Source:          cloudEventSource,
+		ID:              ev.ID,
+		Time:            ev.At,
+		Subject:         ev.Task.ID,
+		DataContentType: "application/json",
+		Data:            ev.Task,
+	}
+}
+
+// formatEvent returns ev as it is sent to SSE and WebSocket clients in the
+// given format: the TaskEvent itself when raw.
+func formatEvent(ev TaskEvent, format string) any {
+	if format == EventFormatCloudEvents {
+		return newCloudEvent(ev)
+	}
+	return ev
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	errCommentNotFound = errors.New("comment not found")
+	errNotAuthor       = errors.New("comment belongs to another user")
+)
+
+// ListComments returns the comments on an active task, oldest first.
+func (h *TaskHandler) ListComments(c *gin.Context) {
+	task, found, err := h.repo.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, 500, CodeInternal, err.Error())
+		return
+	}
+	if !found || task.DeletedAt != nil {
+		respondError(c, 404, CodeTaskNotFound, "task not found")
+		return
+	}
+	c.JSON(200, task.Comments)
+}
+
+// CreateComment appends a comment to a task. With auth enabled the author is
+// the token subject; otherwise it is taken from the body.
+func (h *TaskHandler) CreateComment(c *gin.Context) {
+	var cm Comment
+	if err := bindComment(c, &cm); err != nil {
+		respondDecodeError(c, err)
+		return
+	}
+	cm.ID = uuid.NewString()
+	if subject := c.GetString(subjectKey); subject != "" {
+		cm.Author = subject
+	}
+	cm.CreatedAt = time.Now().UTC()
+	_, found, err := h.modifyComments(c, func(comments []Comment) ([]Comment, error) {
+		return append(comments, cm), nil
+	})
+	if !respondCommentError(c, found, err) {
+		return
+	}
+	c.Header("Location", c.Request.URL.Path+"/"+cm.ID)
+	c.JSON(201, cm)
+}
+
+// DeleteComment removes a comment. With auth enabled only its author may.
+func (h *TaskHandler) DeleteComment(c *gin.Context) {
+	id := c.Param("commentId")
+	subject := c.GetString(subjectKey)
+	_, found, err := h.modifyComments(c, func(comments []Comment) ([]Comment, error) {
+		for i := range comments {
+			if comments[i].ID != id {
+				continue
+			}
+			if subject != "" && comments[i].Author != subject {
+				return nil, errNotAuthor
+			}
+			return append(comments[:i], comments[i+1:]...), nil
+		}
+		return nil, errCommentNotFound
+	})
+	if !respondCommentError(c, found, err) {
+		return
+	}
+	c.Status(204)
+}
+
+// bindComment decodes and validates a comment body. Its id and created_at
+// are ignored.
+func bindComment(c *gin.Context, cm *Comment) error {
+	if err := bindJSON(c, cm); err != nil {
+		return err
+	}
+	return validateComment(*cm)
+}
+
+// modifyComments applies fn to the comments of the active task named by the
+// :id parameter, bumping the task's version. Like modifySubtasks, fn gets a
+// copy of the slice.
+func (h *TaskHandler) modifyComments(c *gin.Context, fn func([]Comment) ([]Comment, error)) (Task, bool, error) {
+	return modifyActive(c.Request.Context(), h.asCaller(c), c.Param("id"), func(t *Task) error {
+		comments, err := fn(append([]Comment{}, t.Comments...))
+		if err != nil {
+			return err
+		}
+		t.Comments = comments
+		t.UpdatedAt = time.Now().UTC()
+		t.Version++
+		return nil
+	})
+}
+
+// respondCommentError reports a failed modifyComments call and returns
+// whether the request may go on to send its success response.
+func respondCommentError(c *gin.Context, found bool, err error) bool {
+	switch {
+	case errors.Is(err, errCommentNotFound):
+		respondError(c, 404, CodeCommentNotFound, err.Error())
+	case errors.Is(err, errNotAuthor):
+		respondError(c, 403, CodeForbidden, err.Error())
+	case err != nil:
+		respondModifyError(c, err)
+	case !found:
+		respondError(c, 404, CodeTaskNotFound, "task not found")
+	default:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComments(t *testing.T) {
+	cfg := testConfig()
+	cfg.JWTSecret = "test-secret"
+	srv := newTestServer(t, cfg)
+	alice := testToken(t, cfg.JWTSecret, "alice")
+
+	resp, b := request(t, srv, "POST", "/v1/tasks", `{"title":"Discuss"}`, "Authorization", alice)
+	if resp.StatusCode != 201 {
+		t.Fatalf("create: status %d: %s", resp.StatusCode, b)
+	}
+	path := "/v1/tasks/" + decode[Task](t, b).ID + "/comments"
+
+	resp, b = request(t, srv, "POST", path, `{"body":"Looks good","author":"mallory"}`, "Authorization", alice)
+	if resp.StatusCode != 201 {
+		t.Fatalf("add: status %d: %s", resp.StatusCode, b)
+	}
+	comment := decode[Comment](t, b)
+	if comment.ID == "" || comment.Author != "alice" || comment.Body != "Looks good" {
+		t.Errorf("comment = %+v, want an id, author alice and the body", comment)
+	}
+
+	resp, b = request(t, srv, "GET", path, "")
+	if got := decode[[]Comment](t, b); resp.StatusCode != 200 || len(got) != 1 || got[0].ID != comment.ID {
+		t.Fatalf("list: status %d: %s", resp.StatusCode, b)
+	}
+
+	resp, _ = request(t, srv, "DELETE", path+"/"+comment.ID, "", "Authorization", alice)
+	if resp.StatusCode != 204 {
+		t.Errorf("delete: status %d, want 204", resp.StatusCode)
+	}
+	if _, b = request(t, srv, "GET", path, ""); len(decode[[]Comment](t, b)) != 0 {
+		t.Errorf("comments after delete: %s", b)
+	}
+
+	if resp, _ = request(t, srv, "POST", "/v1/tasks/missing/comments", `{"body":"x"}`, "Authorization", alice); resp.StatusCode != 404 {
+		t.Errorf("comment on an unknown task: status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestCommentValidation(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	path := "/v1/tasks/" + createTask(t, srv, `{"title":"Discuss"}`).ID + "/comments"
+
+	for _, body := range []string{`{"body":""}`, `{"body":"   "}`, `{"body":"` + strings.Repeat("x", maxCommentLength+1) + `"}`} {
+		resp, b := request(t, srv, "POST", path, body)
+		if resp.StatusCode != 422 {
+			t.Errorf("POST %.30s: status %d, want 422", body, resp.StatusCode)
+			continue
+		}
+		got := decode[struct {
+			Error struct {
+				Code    string
+				Details []FieldError
+			}
+		}](t, b).Error
+		if got.Code != CodeValidationFailed || len(got.Details) != 1 || got.Details[0].Field != "body" {
+			t.Errorf("POST %.30s: %s, want VALIDATION_FAILED naming body", body, b)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+// Completion modes. A manual task's done flag is set on its own, as it
+// always has been; an auto task's follows its subtasks.
+const (
+	CompletionManual = "manual"
+	CompletionAuto   = "auto"
+)
+
+var completionModes = []string{CompletionManual, CompletionAuto}
+
+func isCompletionMode(s string) bool {
+	return containsString(completionModes, s)
+}
+
+// settleCompletion applies the auto completion rules to t after a change,
+// given whether it was done before; a new task counts as not done. Manual
+// tasks, and auto tasks without subtasks, are left as they are.
+//
+// When the change flips the task's own done flag, every subtask follows it:
+// completing the task completes them all and reopening it reopens them all.
+// Otherwise the task is done exactly when all its subtasks are, so checking
+// off the last open subtask completes it, and adding or reopening a subtask
+// of a done task reopens it.
+func settleCompletion(t *Task, wasDone bool) {
+	if t.CompletionMode != CompletionAuto || len(t.Subtasks) == 0 {
+		return
+	}
+	if t.Done != wasDone {
+		subs := make([]Subtask, len(t.Subtasks))
+		for i, s := range t.Subtasks {
+			s.Done = t.Done
+			subs[i] = s
+		}
+		t.Subtasks = subs
+		return
+	}
+	t.Done = true
+	for _, s := range t.Subtasks {
+		if !s.Done {
+			t.Done = false
+			break
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSettleCompletion(t *testing.T) {
+	open, done := Subtask{ID: "o"}, Subtask{ID: "d", Done: true}
+	cases := []struct {
+		name     string
+		mode     string
+		wasDone  bool
+		done     bool
+		subtasks []Subtask
+		wantDone bool
+		wantSubs []bool
+	}{
+		{"manual is left alone", CompletionManual, false, false, []Subtask{done}, false, []bool{true}},
+		{"manual completion keeps subtasks", CompletionManual, false, true, []Subtask{open}, true, []bool{false}},
+		{"auto without subtasks keeps done", CompletionAuto, false, true, nil, true, nil},
+		{"completing cascades", CompletionAuto, false, true, []Subtask{open, done}, true, []bool{true, true}},
+		{"reopening cascades", CompletionAuto, true, false, []Subtask{done, done}, false, []bool{false, false}},
+		{"last subtask done completes", CompletionAuto, false, false, []Subtask{done, done}, true, []bool{true, true}},
+		{"open subtask reopens", CompletionAuto, true, true, []Subtask{done, open}, false, []bool{true, false}},
+		{"open subtasks keep it open", CompletionAuto, false, false, []Subtask{open, done}, false, []bool{false, true}},
+	}
+	for _, tc := range cases {
+		task := Task{CompletionMode: tc.mode, Done: tc.done, Subtasks: append([]Subtask{}, tc.subtasks...)}
+		settleCompletion(&task, tc.wasDone)
+		if task.Done != tc.wantDone {
+			t.Errorf("%s: done = %v, want %v", tc.name, task.Done, tc.wantDone)
+		}
+		for i, want := range tc.wantSubs {
+			if task.Subtasks[i].Done != want {
+				t.Errorf("%s: subtask %d done = %v, want %v", tc.name, i, task.Subtasks[i].Done, want)
+			}
+		}
+	}
+	if !done.Done || open.Done {
+		t.Error("settleCompletion changed the subtasks it was given")
+	}
+}
+
+func TestAutoCompletion(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	task := createTask(t, srv, `{"title":"Release","completion_mode":"auto","subtasks":[{"title":"Build"},{"title":"Tag"}]}`)
+	path := "/v1/tasks/" + task.ID
+	get := func() Task {
+		t.Helper()
+		_, b := request(t, srv, "GET", path, "")
+		return decode[Task](t, b)
+	}
+	send := func(method, p, body string, want int) {
+		t.Helper()
+		if resp, b := request(t, srv, method, p, body); resp.StatusCode != want {
+			t.Fatalf("%s %s: status %d, want %d: %s", method, p, resp.StatusCode, want, b)
+		}
+	}
+	if task.Done {
+		t.Fatal("created done with open subtasks")
+	}
+
+	for _, sub := range task.Subtasks {
+		send("PUT", path+"/subtasks/"+sub.ID, `{"title":"`+sub.Title+`","done":true}`, 200)
+	}
+	if !get().Done {
+		t.Error("ticking off every subtask didn't complete the task")
+	}
+
+	send("POST", path+"/subtasks", `{"title":"Announce"}`, 201)
+	if got := get(); got.Done {
+		t.Error("adding an open subtask to a done task didn't reopen it")
+	}
+
+	send("PATCH", path, `{"done":true}`, 200)
+	for _, sub := range get().Subtasks {
+		if !sub.Done {
+			t.Errorf("completing the task left subtask %q open", sub.Title)
+		}
+	}
+	send("POST", path+"/toggle", "", 200)
+	got := get()
+	for _, sub := range got.Subtasks {
+		if sub.Done {
+			t.Errorf("reopening the task left subtask %q done", sub.Title)
+		}
+	}
+
+	last := got.Subtasks[len(got.Subtasks)-1]
+	send("PUT", path+"/subtasks/"+got.Subtasks[0].ID, `{"title":"Build","done":true}`, 200)
+	send("PUT", path+"/subtasks/"+got.Subtasks[1].ID, `{"title":"Tag","done":true}`, 200)
+	send("DELETE", path+"/subtasks/"+last.ID, "", 204)
+	if !get().Done {
+		t.Error("removing the only open subtask didn't complete the task")
+	}
+}
+
+func TestManualCompletion(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	task := createTask(t, srv, `{"title":"Chores","subtasks":[{"title":"Dishes","done":true}]}`)
+	if task.Done || task.CompletionMode != CompletionManual {
+		t.Fatalf("created %+v, want an open manual task", task)
+	}
+	resp, b := request(t, srv, "PATCH", "/v1/tasks/"+task.ID, `{"done":true}`)
+	if resp.StatusCode != 200 {
+		t.Fatalf("patch: status %d: %s", resp.StatusCode, b)
+	}
+	resp, b = request(t, srv, "PATCH", "/v1/tasks/"+task.ID, `{"done":false}`)
+	if got := decode[Task](t, b); resp.StatusCode != 200 || !got.Subtasks[0].Done {
+		t.Errorf("reopening a manual task changed its subtasks: %s", b)
+	}
+
+	resp, b = request(t, srv, "PATCH", "/v1/tasks/"+task.ID, `{"completion_mode":"auto"}`)
+	if got := decode[Task](t, b); resp.StatusCode != 200 || !got.Done {
+		t.Errorf("switching to auto with every subtask done: status %d, done %v; want 200 and true", resp.StatusCode, got.Done)
+	}
+}
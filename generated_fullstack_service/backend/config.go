@@ -0,0 +1,543 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultConfigFile     = "config.yaml"
+	defaultPort           = 8080
+	defaultRateLimitRPS   = 10
+	defaultRateLimitBurst = 20
+	defaultRedirectPort   = 80
+	// defaultMaxBodyBytes caps JSON request bodies.
+	defaultMaxBodyBytes      = 1 << 20
+	defaultStoreOpenAttempts = 5
+	// storeRetryDelay is the wait before the first retry of opening the
+	// store; it doubles after each failed attempt, up to
+	// storeRetryMaxDelay.
+	storeRetryDelay    = 500 * time.Millisecond
+	storeRetryMaxDelay = 30 * time.Second
+)
+
+// Config holds every server setting. Values come from defaults, then the
+// YAML config file, then environment variables, each overriding the last.
+type Config struct {
+	Port        int    `yaml:"port"`
+	Store       string `yaml:"store"`
+	DBPath      string `yaml:"db_path"`
+	DatabaseURL string `yaml:"database_url"`
+	// StoreOpenAttempts is how many times startup tries to open the store
+	// before giving up.
+	StoreOpenAttempts int    `yaml:"store_open_attempts"`
+	JWTSecret         string `yaml:"jwt_secret"`
+	// BasicAuth is a simpler alternative to JWTSecret; only one may be set.
+	BasicAuth BasicAuthConfig `yaml:"basic_auth"`
+	// CORSOrigins lists the allowed origins; "*" allows any.
+	CORSOrigins []string `yaml:"cors_origins"`
+	// TrustedProxies are the IPs and CIDR ranges whose X-Forwarded-For and
+	// X-Real-IP headers name the client. Requests from anywhere else are
+	// keyed by their own address, so rate limits can't be dodged by
+	// sending a different header each time. Empty trusts no proxy.
+	TrustedProxies []string        `yaml:"trusted_proxies"`
+	RateLimit      RateLimitConfig `yaml:"rate_limit"`
+	TLS            TLSConfig       `yaml:"tls"`
+	MaxBodyBytes   int64           `yaml:"max_body_bytes"`
+	ImportMaxBytes int64           `yaml:"import_max_bytes"`
+	// RequestTimeout of 0 disables the timeout.
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// CacheTTL is how long task reads are cached; 0 disables the cache.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+	LogLevel string        `yaml:"log_level"`
+	// LogFormat is json or text.
+	LogFormat string        `yaml:"log_format"`
+	Webhooks  WebhookConfig `yaml:"webhooks"`
+	// EventFormat is raw or cloudevents, for webhooks, the event stream
+	// and WebSockets alike.
+	EventFormat string `yaml:"event_format"`
+	// TracingEndpoint is the OTLP/HTTP collector URL spans are exported
+	// to; empty disables tracing.
+	TracingEndpoint string `yaml:"tracing_endpoint"`
+	// Dedup makes POST /tasks return an open task with the same title
+	// instead of creating a duplicate.
+	Dedup bool `yaml:"dedup"`
+	// DuplicateWarning makes POST /tasks create such a task anyway, with a
+	// Warning header and the existing task's id.
+	DuplicateWarning bool `yaml:"duplicate_warning"`
+	// MaxTasks caps the number of active tasks; 0 means no limit.
+	MaxTasks int `yaml:"max_tasks"`
+	// DefaultPageLimit is the page size of lists requested without a
+	// limit, at most maxPageLimit.
+	DefaultPageLimit int `yaml:"default_page_limit"`
+	// SlowRequestMS is the latency above which a request is logged as a
+	// warning; 0 disables the warning.
+	SlowRequestMS int `yaml:"slow_request_ms"`
+	// ArchiveAfterDays is how long a done task must go without updates
+	// before the archiver takes it; 0 disables the archiver, as does an
+	// ArchiveInterval of 0.
+	ArchiveAfterDays int           `yaml:"archive_after_days"`
+	ArchiveInterval  time.Duration `yaml:"archive_interval"`
+	// AdminToken enables POST /v1/admin/reset and the /v1/admin/config
+	// routes for requests that send it in X-Admin-Token; empty leaves them
+	// out.
+	AdminToken string `yaml:"admin_token"`
+	// ReadOnly starts the server refusing writes, for maintenance; it can
+	// be switched off at runtime.
+	ReadOnly bool `yaml:"read_only"`
+	// RequireContentType makes the task routes refuse request bodies
+	// without a Content-Type instead of reading them as JSON.
+	RequireContentType bool `yaml:"require_content_type"`
+	// Seed fills an empty store with sample tasks at startup.
+	Seed bool `yaml:"seed"`
+	// EnablePprof serves the runtime profiles under /debug/pprof.
+	EnablePprof bool `yaml:"enable_pprof"`
+	// DevMode exposes internals useful while developing, such as panic
+	// stacks in error responses. It must be off in production.
+	DevMode bool `yaml:"dev_mode"`
+	// OwnerOnlyWrites limits changes to a task to its owner. It needs JWT
+	// auth, which is what identifies the owner.
+	OwnerOnlyWrites bool `yaml:"owner_only_writes"`
+}
+
+// WebhookConfig lists the URLs notified of task changes and the optional
+// secret their payloads are signed with.
+type WebhookConfig struct {
+	URLs   []string `yaml:"urls"`
+	Secret string   `yaml:"secret"`
+}
+
+// TLSConfig serves HTTPS when both Cert and Key are set. Redirect also
+// listens for plain HTTP on RedirectPort and sends every request there to
+// HTTPS.
+type TLSConfig struct {
+	Cert         string `yaml:"cert"`
+	Key          string `yaml:"key"`
+	Redirect     bool   `yaml:"redirect"`
+	RedirectPort int    `yaml:"redirect_port"`
+}
+
+// Enabled reports whether the server should serve HTTPS.
+func (t TLSConfig) Enabled() bool {
+	return t.Cert != "" && t.Key != ""
+}
+
+// BasicAuthConfig puts every route but /health behind HTTP Basic
+// authentication when both User and Password are set.
+type BasicAuthConfig struct {
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+}
+
+// Enabled reports whether Basic authentication is required.
+func (b BasicAuthConfig) Enabled() bool {
+	return b.User != "" && b.Password != ""
+}
+
+// RateLimitConfig is the per-client token bucket. An RPS of 0 disables rate
+// limiting.
+type RateLimitConfig struct {
+	RPS   int `yaml:"rps"`
+	Burst int `yaml:"burst"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		Port:              defaultPort,
+		Store:             "sqlite",
+		DBPath:            "tasks.db",
+		StoreOpenAttempts: defaultStoreOpenAttempts,
+		CORSOrigins:       []string{"*"},
+		RateLimit:         RateLimitConfig{RPS: defaultRateLimitRPS, Burst: defaultRateLimitBurst},
+		TLS:               TLSConfig{RedirectPort: defaultRedirectPort},
+		MaxBodyBytes:      defaultMaxBodyBytes,
+		ImportMaxBytes:    defaultImportMaxBytes,
+		DefaultPageLimit:  defaultPageLimit,
+		RequestTimeout:    defaultRequestTimeout,
+		LogLevel:          "info",
+		LogFormat:         "json",
+		SlowRequestMS:     defaultSlowRequestMS,
+		EventFormat:       EventFormatRaw,
+		ArchiveInterval:   defaultArchiveInterval,
+	}
+}
+
+// LoadConfig builds the configuration from the YAML file named by
+// CONFIG_FILE (default config.yaml) and the environment. The default file
+// may be absent; one named explicitly must exist.
+func LoadConfig() (Config, error) {
+	cfg := defaultConfig()
+
+	path, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit {
+		path = defaultConfigFile
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && !explicit:
+	case err != nil:
+		return cfg, err
+	default:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// applyEnv overrides cfg with any of the corresponding environment variables
+// that are set.
+func (cfg *Config) applyEnv() error {
+	envString("STORE", &cfg.Store)
+	envString("DB_PATH", &cfg.DBPath)
+	envString("DATABASE_URL", &cfg.DatabaseURL)
+	envString("JWT_SECRET", &cfg.JWTSecret)
+	envString("BASIC_AUTH_USER", &cfg.BasicAuth.User)
+	envString("BASIC_AUTH_PASS", &cfg.BasicAuth.Password)
+	envString("ADMIN_TOKEN", &cfg.AdminToken)
+	envString("LOG_LEVEL", &cfg.LogLevel)
+	envString("LOG_FORMAT", &cfg.LogFormat)
+	envString("WEBHOOK_SECRET", &cfg.Webhooks.Secret)
+	envString("EVENT_FORMAT", &cfg.EventFormat)
+	envString("OTEL_EXPORTER_OTLP_ENDPOINT", &cfg.TracingEndpoint)
+	envString("TLS_CERT", &cfg.TLS.Cert)
+	envString("TLS_KEY", &cfg.TLS.Key)
+	envList("CORS_ORIGINS", &cfg.CORSOrigins)
+	envList("TRUSTED_PROXIES", &cfg.TrustedProxies)
+	envList("WEBHOOK_URLS", &cfg.Webhooks.URLs)
+
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"PORT", &cfg.Port},
+		{"RATE_LIMIT_RPS", &cfg.RateLimit.RPS},
+		{"RATE_LIMIT_BURST", &cfg.RateLimit.Burst},
+		{"TLS_REDIRECT_PORT", &cfg.TLS.RedirectPort},
+		{"MAX_TASKS", &cfg.MaxTasks},
+		{"DEFAULT_PAGE_LIMIT", &cfg.DefaultPageLimit},
+		{"SLOW_REQUEST_MS", &cfg.SlowRequestMS},
+		{"ARCHIVE_AFTER_DAYS", &cfg.ArchiveAfterDays},
+		{"STORE_OPEN_ATTEMPTS", &cfg.StoreOpenAttempts},
+	}
+	for _, e := range ints {
+		if err := envInt(e.name, e.dst); err != nil {
+			return err
+		}
+	}
+	if err := envInt64("MAX_BODY_BYTES", &cfg.MaxBodyBytes); err != nil {
+		return err
+	}
+	if err := envInt64("IMPORT_MAX_BYTES", &cfg.ImportMaxBytes); err != nil {
+		return err
+	}
+	if err := envBool("OWNER_ONLY_WRITES", &cfg.OwnerOnlyWrites); err != nil {
+		return err
+	}
+	if err := envBool("DEV_MODE", &cfg.DevMode); err != nil {
+		return err
+	}
+	if err := envBool("DEDUP", &cfg.Dedup); err != nil {
+		return err
+	}
+	if err := envBool("DUPLICATE_WARNING", &cfg.DuplicateWarning); err != nil {
+		return err
+	}
+	if err := envBool("TLS_REDIRECT", &cfg.TLS.Redirect); err != nil {
+		return err
+	}
+	if err := envBool("ENABLE_PPROF", &cfg.EnablePprof); err != nil {
+		return err
+	}
+	if err := envBool("READ_ONLY", &cfg.ReadOnly); err != nil {
+		return err
+	}
+	if err := envBool("REQUIRE_CONTENT_TYPE", &cfg.RequireContentType); err != nil {
+		return err
+	}
+	if err := envBool("SEED", &cfg.Seed); err != nil {
+		return err
+	}
+	if err := envDuration("REQUEST_TIMEOUT", &cfg.RequestTimeout); err != nil {
+		return err
+	}
+	if err := envDuration("ARCHIVE_INTERVAL", &cfg.ArchiveInterval); err != nil {
+		return err
+	}
+	return envDuration("CACHE_TTL", &cfg.CacheTTL)
+}
+
+func envString(name string, dst *string) {
+	if v := os.Getenv(name); v != "" {
+		*dst = v
+	}
+}
+
+// envList reads a comma-separated list, skipping blank entries.
+func envList(name string, dst *[]string) {
+	v := os.Getenv(name)
+	if v == "" {
+		return
+	}
+	*dst = nil
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*dst = append(*dst, item)
+		}
+	}
+}
+
+func envInt(name string, dst *int) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: must be an integer", name, v)
+	}
+	*dst = n
+	return nil
+}
+
+func envInt64(name string, dst *int64) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: must be an integer", name, v)
+	}
+	*dst = n
+	return nil
+}
+
+func envBool(name string, dst *bool) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: must be true or false", name, v)
+	}
+	*dst = b
+	return nil
+}
+
+func envDuration(name string, dst *time.Duration) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: must be a duration such as 30s", name, v)
+	}
+	*dst = d
+	return nil
+}
+
+// validate reports the first invalid setting, named by its config file key.
+func (cfg Config) validate() error {
+	switch {
+	case cfg.Port < 1 || cfg.Port > 65535:
+		return fmt.Errorf("invalid port %d: must be between 1 and 65535", cfg.Port)
+	case cfg.Store != "sqlite" && cfg.Store != "postgres" && cfg.Store != "memory":
+		return fmt.Errorf("invalid store %q: must be sqlite, postgres or memory", cfg.Store)
+	case cfg.Store == "sqlite" && cfg.DBPath == "":
+		return errors.New("db_path is required when store is sqlite")
+	case cfg.Store == "postgres" && cfg.DatabaseURL == "":
+		return errors.New("database_url is required when store is postgres")
+	case cfg.StoreOpenAttempts < 1:
+		return fmt.Errorf("invalid store_open_attempts %d: must be at least 1", cfg.StoreOpenAttempts)
+	case len(cfg.CORSOrigins) == 0:
+		return errors.New("cors_origins must list at least one origin")
+	case cfg.RateLimit.RPS < 0:
+		return fmt.Errorf("invalid rate_limit.rps %d: must not be negative", cfg.RateLimit.RPS)
+	case cfg.RateLimit.RPS > 0 && cfg.RateLimit.Burst < 1:
+		return fmt.Errorf("invalid rate_limit.burst %d: must be at least 1", cfg.RateLimit.Burst)
+	case cfg.MaxBodyBytes < 1:
+		return fmt.Errorf("invalid max_body_bytes %d: must be positive", cfg.MaxBodyBytes)
+	case cfg.ImportMaxBytes < 1:
+		return fmt.Errorf("invalid import_max_bytes %d: must be positive", cfg.ImportMaxBytes)
+	case cfg.RequestTimeout < 0:
+		return fmt.Errorf("invalid request_timeout %s: must not be negative", cfg.RequestTimeout)
+	case cfg.CacheTTL < 0:
+		return fmt.Errorf("invalid cache_ttl %s: must not be negative", cfg.CacheTTL)
+	case cfg.MaxTasks < 0:
+		return fmt.Errorf("invalid max_tasks %d: must not be negative", cfg.MaxTasks)
+	case cfg.DefaultPageLimit < 1 || cfg.DefaultPageLimit > maxPageLimit:
+		return fmt.Errorf("invalid default_page_limit %d: must be between 1 and %d", cfg.DefaultPageLimit, maxPageLimit)
+	case cfg.SlowRequestMS < 0:
+		return fmt.Errorf("invalid slow_request_ms %d: must not be negative", cfg.SlowRequestMS)
+	case cfg.ArchiveAfterDays < 0:
+		return fmt.Errorf("invalid archive_after_days %d: must not be negative", cfg.ArchiveAfterDays)
+	case cfg.ArchiveInterval < 0:
+		return fmt.Errorf("invalid archive_interval %s: must not be negative", cfg.ArchiveInterval)
+	case cfg.LogFormat != "json" && cfg.LogFormat != "text":
+		return fmt.Errorf("invalid log_format %q: must be json or text", cfg.LogFormat)
+	case cfg.EventFormat != EventFormatRaw && cfg.EventFormat != EventFormatCloudEvents:
+		return fmt.Errorf("invalid event_format %q: must be raw or cloudevents", cfg.EventFormat)
+	case (cfg.BasicAuth.User == "") != (cfg.BasicAuth.Password == ""):
+		return errors.New("basic_auth.user and basic_auth.password must be set together")
+	case cfg.BasicAuth.Enabled() && cfg.JWTSecret != "":
+		return errors.New("basic_auth and jwt_secret are mutually exclusive: configure only one")
+	case cfg.OwnerOnlyWrites && cfg.JWTSecret == "":
+		return errors.New("owner_only_writes requires jwt_secret")
+	case (cfg.TLS.Cert == "") != (cfg.TLS.Key == ""):
+		return errors.New("tls.cert and tls.key must be set together")
+	case cfg.TLS.Redirect && !cfg.TLS.Enabled():
+		return errors.New("tls.redirect requires tls.cert and tls.key")
+	case cfg.TLS.Redirect && (cfg.TLS.RedirectPort < 1 || cfg.TLS.RedirectPort > 65535 || cfg.TLS.RedirectPort == cfg.Port):
+		return fmt.Errorf("invalid tls.redirect_port %d: must be between 1 and 65535 and differ from port", cfg.TLS.RedirectPort)
+	}
+	if cfg.TLS.Enabled() {
+		if err := checkTLSFiles(cfg.TLS); err != nil {
+			return err
+		}
+	}
+	for _, p := range cfg.TrustedProxies {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			return fmt.Errorf("invalid trusted_proxies entry %q: must be an IP address or CIDR range", p)
+		}
+	}
+	for _, u := range cfg.Webhooks.URLs {
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid webhooks.urls entry %q: must be an http or https URL", u)
+		}
+	}
+	_, err := cfg.parseLogLevel()
+	return err
+}
+
+// checkTLSFiles makes a missing or unreadable certificate fail at startup
+// rather than on the first handshake.
+func checkTLSFiles(t TLSConfig) error {
+	for _, f := range []struct{ key, path string }{{"tls.cert", t.Cert}, {"tls.key", t.Key}} {
+		if _, err := os.Stat(f.path); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", f.key, f.path, err)
+		}
+	}
+	if _, err := tls.LoadX509KeyPair(t.Cert, t.Key); err != nil {
+		return fmt.Errorf("invalid tls.cert/tls.key: %w", err)
+	}
+	return nil
+}
+
+// Addr is the address to serve on.
+func (cfg Config) Addr() string {
+	return ":" + strconv.Itoa(cfg.Port)
+}
+
+// Level is the parsed LogLevel; validate has already rejected bad values.
+func (cfg Config) Level() slog.Level {
+	level, _ := cfg.parseLogLevel()
+	return level
+}
+
+func (cfg Config) parseLogLevel() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return 0, fmt.Errorf("invalid log_level %q: must be debug, info, warn or error", cfg.LogLevel)
+	}
+	return level, nil
+}
+
+// openStore opens the backend selected by cfg.Store. The returned function
+// releases it.
+func openStore(ctx context.Context, cfg Config) (Store, func() error, error) {
+	switch cfg.Store {
+	case "postgres":
+		s, err := NewPostgresStore(ctx, cfg.DatabaseURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("connect to postgres: %w", err)
+		}
+		return s, s.Close, nil
+	case "memory":
+		return NewTaskStore(), func() error { return nil }, nil
+	default:
+		s, err := NewSQLiteStore(cfg.DBPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open database %s: %w", cfg.DBPath, err)
+		}
+		return s, s.Close, nil
+	}
+}
+
+// connectStore opens the store with openStore, retrying as retryOpenStore
+// describes, so a database that is still starting up doesn't stop the
+// server. A store that can't be opened is a startup error: the server exits
+// rather than run without one, leaving restarts to whatever supervises it,
+// and never serves a request it has no store for.
+func connectStore(ctx context.Context, cfg Config) (Store, func() error, error) {
+	return retryOpenStore(ctx, cfg, openStore, sleepContext)
+}
+
+// retryOpenStore calls open until it succeeds, for cfg.StoreOpenAttempts
+// attempts at most, waiting with sleep for storeRetryDelay after the first
+// failure and twice as long after each one after that, up to
+// storeRetryMaxDelay. Only a database that couldn't be reached is retried;
+// one that was reached but failed a migration, say, fails at once, since
+// trying again would only fail the same way.
+func retryOpenStore(ctx context.Context, cfg Config,
+	open func(context.Context, Config) (Store, func() error, error),
+	sleep func(context.Context, time.Duration) error,
+) (Store, func() error, error) {
+	delay := storeRetryDelay
+	for attempt := 1; ; attempt++ {
+		store, closeStore, err := open(ctx, cfg)
+		if err == nil {
+			return store, closeStore, nil
+		}
+		if !storeUnavailable(err) {
+			return nil, nil, err
+		}
+		if attempt == cfg.StoreOpenAttempts {
+			return nil, nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		slog.Warn("store not opened; retrying", "attempt", attempt, "retry_in", delay, "error", err)
+		if sleep(ctx, delay) != nil {
+			return nil, nil, fmt.Errorf("%w (interrupted while retrying)", err)
+		}
+		delay = min(delay*2, storeRetryMaxDelay)
+	}
+}
+
+// storeUnavailable reports whether an openStore error means the database
+// couldn't be reached, which retrying may fix.
+func storeUnavailable(err error) bool {
+	return pgUnavailable(err) || sqliteUnavailable(err)
+}
+
+// sleepContext waits for d, returning early with ctx's error if it is done
+// first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
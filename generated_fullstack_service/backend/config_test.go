@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestDefaultPageLimitConfig(t *testing.T) {
+	t.Setenv("DEFAULT_PAGE_LIMIT", "5")
+	cfg := defaultConfig()
+	if err := cfg.applyEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DefaultPageLimit != 5 {
+		t.Errorf("DefaultPageLimit = %d, want 5 from the environment", cfg.DefaultPageLimit)
+	}
+
+	for _, n := range []int{0, -1, maxPageLimit + 1} {
+		cfg := defaultConfig()
+		cfg.DefaultPageLimit = n
+		if err := cfg.validate(); err == nil {
+			t.Errorf("default_page_limit %d was accepted", n)
+		}
+	}
+	cfg = defaultConfig()
+	cfg.DefaultPageLimit = maxPageLimit
+	if err := cfg.validate(); err != nil {
+		t.Errorf("default_page_limit %d: %v", maxPageLimit, err)
+	}
+}
+
+func TestDefaultPageLimit(t *testing.T) {
+	cfg := testConfig()
+	cfg.DefaultPageLimit = 3
+	srv := newTestServer(t, cfg)
+	for i := 0; i < 5; i++ {
+		createTask(t, srv, `{"title":"Task `+strconv.Itoa(i)+`"}`)
+	}
+
+	resp, b := request(t, srv, "GET", "/v1/tasks", "")
+	if got := decode[[]Task](t, b); len(got) != 3 || resp.Header.Get("X-Limit") != "3" {
+		t.Errorf("list without a limit: %d tasks, X-Limit %q; want 3", len(got), resp.Header.Get("X-Limit"))
+	}
+	if _, b := request(t, srv, "GET", "/v1/tasks?limit=4", ""); len(decode[[]Task](t, b)) != 4 {
+		t.Errorf("?limit=4 returned %s", b)
+	}
+}
+
+// flakyOpener fails with errs in turn, then opens an in-memory store. It
+// counts its calls.
+type flakyOpener struct {
+	errs  []error
+	calls int
+}
+
+func (o *flakyOpener) open(context.Context, Config) (Store, func() error, error) {
+	o.calls++
+	if o.calls <= len(o.errs) {
+		return nil, nil, o.errs[o.calls-1]
+	}
+	return NewTaskStore(), func() error { return nil }, nil
+}
+
+// recordSleeps returns a sleep function that records the delays asked for
+// without waiting.
+func recordSleeps(delays *[]time.Duration) func(context.Context, time.Duration) error {
+	return func(_ context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return nil
+	}
+}
+
+var errConnRefused = fmt.Errorf("connect to postgres: %w", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED})
+
+func TestRetryOpenStore(t *testing.T) {
+	ctx := context.Background()
+	cfg := defaultConfig()
+
+	t.Run("retries until the store opens", func(t *testing.T) {
+		opener := &flakyOpener{errs: []error{errConnRefused, errConnRefused}}
+		var delays []time.Duration
+		store, _, err := retryOpenStore(ctx, cfg, opener.open, recordSleeps(&delays))
+		if err != nil || store == nil {
+			t.Fatalf("got %v, %v; want a store", store, err)
+		}
+		if opener.calls != 3 || !slices.Equal(delays, []time.Duration{storeRetryDelay, 2 * storeRetryDelay}) {
+			t.Errorf("%d calls, delays %v; want 3 calls and delays doubling from %s", opener.calls, delays, storeRetryDelay)
+		}
+	})
+
+	t.Run("caps the delay and gives up", func(t *testing.T) {
+		cfg := cfg
+		cfg.StoreOpenAttempts = 10
+		opener := &flakyOpener{errs: make([]error, 10)}
+		for i := range opener.errs {
+			opener.errs[i] = errConnRefused
+		}
+		var delays []time.Duration
+		_, _, err := retryOpenStore(ctx, cfg, opener.open, recordSleeps(&delays))
+		if !errors.Is(err, syscall.ECONNREFUSED) || !strings.Contains(err.Error(), "giving up after 10 attempts") {
+			t.Errorf("err = %v, want giving up with the last error", err)
+		}
+		if opener.calls != 10 || len(delays) != 9 {
+			t.Fatalf("%d calls, %d sleeps; want 10 and 9", opener.calls, len(delays))
+		}
+		for i, d := range delays {
+			if d > storeRetryMaxDelay {
+				t.Errorf("delay %d is %s, above the cap of %s", i, d, storeRetryMaxDelay)
+			}
+		}
+		if last := delays[len(delays)-1]; last != storeRetryMaxDelay {
+			t.Errorf("last delay %s, want the cap %s", last, storeRetryMaxDelay)
+		}
+	})
+
+	t.Run("fails at once on a migration error", func(t *testing.T) {
+		migration := fmt.Errorf("connect to postgres: migration 3_add_column: %w", &pgconn.PgError{Code: "42P01", Message: "relation does not exist"})
+		opener := &flakyOpener{errs: []error{migration}}
+		var delays []time.Duration
+		if _, _, err := retryOpenStore(ctx, cfg, opener.open, recordSleeps(&delays)); !errors.Is(err, migration) {
+			t.Errorf("err = %v, want the migration error", err)
+		}
+		if opener.calls != 1 || len(delays) != 0 {
+			t.Errorf("%d calls, %d sleeps; want 1 and none", opener.calls, len(delays))
+		}
+	})
+
+	t.Run("stops when interrupted", func(t *testing.T) {
+		opener := &flakyOpener{errs: []error{errConnRefused, errConnRefused}}
+		interrupted := func(context.Context, time.Duration) error { return context.Canceled }
+		_, _, err := retryOpenStore(ctx, cfg, opener.open, interrupted)
+		if err == nil || !strings.Contains(err.Error(), "interrupted") || opener.calls != 1 {
+			t.Errorf("err = %v after %d calls, want an interruption after 1", err, opener.calls)
+		}
+	})
+}
+
+func TestStoreUnavailable(t *testing.T) {
+	dir := t.TempDir()
+	notADB := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(notADB, []byte(strings.Repeat("not a database ", 100)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	type testCase struct {
+		name string
+		err  error
+		want bool
+	}
+	cases := []testCase{
+		{"refused connection", errConnRefused, true},
+		{"postgres starting up", &pgconn.PgError{Code: "57P03"}, true},
+		{"postgres bad password", &pgconn.PgError{Code: "28P01"}, false},
+		{"plain error", errors.New("boom"), false},
+	}
+	for _, path := range []struct {
+		name, path string
+		want       bool
+	}{
+		{"missing sqlite directory", filepath.Join(dir, "missing", "tasks.db"), true},
+		{"sqlite file that isn't a database", notADB, false},
+	} {
+		cfg := defaultConfig()
+		cfg.DBPath = path.path
+		_, closeStore, err := openStore(context.Background(), cfg)
+		if err == nil {
+			closeStore()
+			t.Fatalf("%s: opened", path.name)
+		}
+		cases = append(cases, testCase{path.name, err, path.want})
+	}
+	for _, tc := range cases {
+		if got := storeUnavailable(tc.err); got != tc.want {
+			t.Errorf("%s: storeUnavailable(%v) = %v, want %v", tc.name, tc.err, got, tc.want)
+		}
+	}
+}
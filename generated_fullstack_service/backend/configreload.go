@@ -0,0 +1,134 @@
+package main
+
+import (
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// hotReloadable are the config file keys a reload applies at once. Changes to
+// any other key wait for a restart.
+var hotReloadable = map[string]bool{"log_level": true, "rate_limit": true, "cors_origins": true}
+
+// redactedConfigKeys are secrets GET /v1/admin/config never shows, as
+// dotted config file keys. The database URL may embed a password.
+var redactedConfigKeys = []string{"jwt_secret", "admin_token", "database_url", "basic_auth.password", "webhooks.secret"}
+
+const redacted = "[redacted]"
+
+// liveConfig is the configuration the server is running with, along with
+// the parts of it that can be switched while serving requests.
+type liveConfig struct {
+	mu        sync.Mutex
+	cfg       Config
+	rateLimit *ipRateLimiter
+	cors      *corsOrigins
+}
+
+func newLiveConfig(cfg Config) *liveConfig {
+	return &liveConfig{
+		cfg:       cfg,
+		rateLimit: newIPRateLimiter(cfg.RateLimit.RPS, cfg.RateLimit.Burst),
+		cors:      newCORSOrigins(cfg.CORSOrigins),
+	}
+}
+
+// current returns the running configuration.
+func (l *liveConfig) current() Config {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cfg
+}
+
+// reload applies the hotReloadable settings of next and returns the keys it
+// changed, along with the keys of next that differ from the running
+// configuration but need a restart.
+func (l *liveConfig) reload(next Config) (applied, pending []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	from, to := configFields(l.cfg), configFields(next)
+	for key, v := range to {
+		if reflect.DeepEqual(from[key], v) {
+			continue
+		}
+		if !hotReloadable[key] {
+			pending = append(pending, key)
+			continue
+		}
+		applied = append(applied, key)
+		switch key {
+		case "log_level":
+			l.cfg.LogLevel = next.LogLevel
+			logLevel.Set(next.Level())
+		case "rate_limit":
+			l.cfg.RateLimit = next.RateLimit
+			l.rateLimit.set(next.RateLimit.RPS, next.RateLimit.Burst)
+		case "cors_origins":
+			l.cfg.CORSOrigins = next.CORSOrigins
+			l.cors.set(next.CORSOrigins)
+		}
+	}
+	sort.Strings(applied)
+	sort.Strings(pending)
+	return applied, pending
+}
+
+// configFields returns cfg keyed as in the config file.
+func configFields(cfg Config) map[string]any {
+	fields := map[string]any{}
+	b, _ := yaml.Marshal(cfg)
+	yaml.Unmarshal(b, &fields)
+	return fields
+}
+
+// redactConfig returns cfg keyed as in the config file, with every secret
+// that is set replaced by a placeholder.
+func redactConfig(cfg Config) map[string]any {
+	fields := configFields(cfg)
+	for _, key := range redactedConfigKeys {
+		m, name := fields, key
+		if parent, child, nested := strings.Cut(key, "."); nested {
+			m, _ = fields[parent].(map[string]any)
+			name = child
+		}
+		if v, _ := m[name].(string); v != "" {
+			m[name] = redacted
+		}
+	}
+	return fields
+}
+
+// registerConfig serves GET /v1/admin/config, the running configuration
+// with its secrets redacted, and POST /v1/admin/config/reload, which reads
+// the config file and environment again, behind AdminToken.
+func registerConfig(r gin.IRouter, live *liveConfig, token string) {
+	r.GET(adminConfigPath, AdminToken(token), func(c *gin.Context) {
+		c.JSON(200, redactConfig(live.current()))
+	})
+	r.POST(adminConfigReloadPath, AdminToken(token), func(c *gin.Context) {
+		next, err := LoadConfig()
+		if err != nil {
+			respondError(c, 422, CodeValidationFailed, "config not reloaded: "+err.Error())
+			return
+		}
+		applied, pending := live.reload(next)
+		slog.Warn("config reloaded", "applied", applied, "restart_required", pending, "request_id", c.GetString(requestIDKey))
+		c.JSON(200, gin.H{
+			"applied":          nonNilStrings(applied),
+			"restart_required": nonNilStrings(pending),
+			"config":           redactConfig(live.current()),
+		})
+	})
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// defaultImportMaxBytes caps the size of a POST /tasks/import upload.
+const defaultImportMaxBytes = 5 << 20
+
+var csvHeader = []string{"id", "title", "done", "priority", "due_date", "tags", "created_at", "updated_at"}
+
+// csvTagSeparator joins a task's tags into a single CSV field.
+const csvTagSeparator = ";"
+
+func csvRecord(t Task) []string {
+	due := ""
+	if t.DueDate != nil {
+		due = t.DueDate.Format(time.RFC3339)
+	}
+	return []string{
+		t.ID,
+		t.Title,
+		strconv.FormatBool(t.Done),
+		t.Priority,
+		due,
+		strings.Join(t.Tags, csvTagSeparator),
+		t.CreatedAt.Format(time.RFC3339),
+		t.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// ExportCSV writes every task matching the list filters as a CSV download.
+// Paging parameters are ignored so the export is always complete.
+func (h *TaskHandler) ExportCSV(c *gin.Context) {
+	opts, err := parseListOptions(c, h.pageLimit)
+	if err != nil {
+		respondError(c, 400, CodeInvalidRequest, err.Error())
+		return
+	}
+	tasks, err := h.repo.List(c.Request.Context())
+	if err != nil {
+		respondError(c, 500, CodeInternal, err.Error())
+		return
+	}
+	tasks = opts.match(tasks)
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="tasks.csv"`)
+	c.Status(200)
+	w := csv.NewWriter(c.Writer)
+	w.Write(csvHeader)
+	for _, t := range tasks {
+		w.Write(csvRecord(t))
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		c.Error(err)
+	}
+}
+
+// ImportCSV creates a task for every valid row of the uploaded CSV file. The
+// file needs a header row with a title column and optionally done and tags
+// columns.
+// Bad rows are reported by line number and skipped; the rest are imported.
+func (h *TaskHandler) ImportCSV(c *gin.Context) {
+	dryRun, ok := parseDryRun(c)
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.importMaxBytes)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(c, 413, CodePayloadTooLarge, fmt.Sprintf("upload exceeds %d bytes", h.importMaxBytes))
+			return
+		}
+		respondError(c, 400, CodeInvalidRequest, "a CSV file is required in the \"file\" form field")
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		respondError(c, 500, CodeInternal, err.Error())
+		return
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		respondError(c, 400, CodeInvalidRequest, "CSV header row is missing or malformed")
+		return
+	}
+	titleCol, doneCol, tagsCol := -1, -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "title":
+			titleCol = i
+		case "done":
+			doneCol = i
+		case "tags":
+			tagsCol = i
+		}
+	}
+	if titleCol < 0 {
+		respondError(c, 400, CodeInvalidRequest, "CSV header must include a title column")
+		return
+	}
+
+	now := time.Now().UTC()
+	var tasks []Task
+	rowErrors := []gin.H{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				respondError(c, 400, CodeInvalidRequest, err.Error())
+				return
+			}
+			rowErrors = append(rowErrors, gin.H{"line": parseErr.Line, "error": parseErr.Err.Error()})
+			continue
+		}
+		line, _ := r.FieldPos(0)
+		task, err := taskFromCSV(record, titleCol, doneCol, tagsCol)
+		if err != nil {
+			rowErrors = append(rowErrors, gin.H{"line": line, "error": err.Error()})
+			continue
+		}
+		task.ID = uuid.NewString()
+		assignOwner(&task, c.GetString(subjectKey))
+		task.CreatedAt, task.UpdatedAt = now, now
+		task.Version = 1
+		tasks = append(tasks, task)
+	}
+
+	if len(tasks) > 0 {
+		if err := h.asCaller(c).CreateMany(c.Request.Context(), tasks); err != nil {
+			respondModifyError(c, err)
+			return
+		}
+		if !dryRun {
+			tasksGauge.Add(float64(len(tasks)))
+		}
+	}
+	c.JSON(200, gin.H{
+		"imported": len(tasks),
+		"failed":   len(rowErrors),
+		"errors":   rowErrors,
+	})
+}
+
+func taskFromCSV(record []string, titleCol, doneCol, tagsCol int) (Task, error) {
+	var task Task
+	if titleCol >= len(record) {
+		return task, errors.New("missing title")
+	}
+	task.Title = record[titleCol]
+	if doneCol >= 0 && doneCol < len(record) {
+		if v := strings.TrimSpace(record[doneCol]); v != "" {
+			done, err := strconv.ParseBool(v)
+			if err != nil {
+				return task, fmt.Errorf("done must be true or false, got %q", v)
+			}
+			task.Done = done
+		}
+	}
+	if tagsCol >= 0 && tagsCol < len(record) {
+		for _, tag := range strings.Split(record[tagsCol], csvTagSeparator) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				task.Tags = append(task.Tags, tag)
+			}
+		}
+	}
+	applyDefaults(&task)
+	return task, validateTask(task)
+}
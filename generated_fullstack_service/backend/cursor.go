@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// defaultCursorSort orders cursor pages when no ?sort= is given.
+const defaultCursorSort = "created_at"
+
+var errInvalidCursor = errors.New("cursor is invalid or was issued for a different sort")
+
+// pageCursor marks the last task of a page in keyset order: the sort it was
+// issued for, that task's value of the sort field, and its id.
+type pageCursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	ID   string `json:"i"`
+}
+
+// encodeCursor returns the opaque cursor a client sends to get the tasks
+// that follow t.
+func encodeCursor(sortSpec string, t Task) string {
+	field, _ := splitSortSpec(sortSpec)
+	b, _ := json.Marshal(pageCursor{Sort: sortSpec, Key: sortKey(t, field), ID: t.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor parses a cursor, which must have been issued for sortSpec.
+func decodeCursor(s, sortSpec string) (pageCursor, error) {
+	var cur pageCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &cur) != nil || cur.Sort != sortSpec {
+		return pageCursor{}, errInvalidCursor
+	}
+	field, _ := splitSortSpec(sortSpec)
+	if _, err := taskWithSortKey(field, cur.Key); err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+	return cur, nil
+}
+
+func splitSortSpec(spec string) (field string, desc bool) {
+	if len(spec) > 0 && spec[0] == '-' {
+		return spec[1:], true
+	}
+	return spec, false
+}
+
+// sortKey returns t's value of a sortable field as a string.
+func sortKey(t Task, field string) string {
+	switch field {
+	case "title":
+		return t.Title
+	case "done":
+		return strconv.FormatBool(t.Done)
+	case "priority":
+		return t.Priority
+	case "updated_at":
+		return t.UpdatedAt.Format(time.RFC3339Nano)
+	case "order":
+		return strconv.Itoa(t.Order)
+	}
+	return t.CreatedAt.Format(time.RFC3339Nano)
+}
+
+// taskWithSortKey is the inverse of sortKey: a task that compares under
+// taskLess like the one key was taken from.
+func taskWithSortKey(field, key string) (Task, error) {
+	var t Task
+	var err error
+	switch field {
+	case "title":
+		t.Title = key
+	case "done":
+		t.Done, err = strconv.ParseBool(key)
+	case "priority":
+		if _, ok := priorityRank[key]; !ok {
+			err = errInvalidCursor
+		}
+		t.Priority = key
+	case "updated_at":
+		t.UpdatedAt, err = time.Parse(time.RFC3339Nano, key)
+	case "order":
+		t.Order, err = strconv.Atoi(key)
+	default:
+		t.CreatedAt, err = time.Parse(time.RFC3339Nano, key)
+	}
+	return t, err
+}
+
+// keysetLess orders tasks by the sort field with the id as a tiebreaker, so
+// every task has a fixed position relative to any cursor.
+func keysetLess(field string, desc bool) func(a, b Task) bool {
+	less := taskLess[field]
+	return func(a, b Task) bool {
+		switch {
+		case less(a, b):
+			return !desc
+		case less(b, a):
+			return desc
+		}
+		return a.ID < b.ID
+	}
+}
+
+// keysetPage sorts tasks into keyset order and returns up to limit of them
+// following cur (or from the start if cur is nil), plus the cursor for the
+// next page, which is empty on the last one.
+func keysetPage(tasks []Task, sortSpec string, cur *pageCursor, limit int) ([]Task, string) {
+	field, desc := splitSortSpec(sortSpec)
+	less := keysetLess(field, desc)
+	sort.Slice(tasks, func(i, j int) bool { return less(tasks[i], tasks[j]) })
+
+	start := 0
+	if cur != nil {
+		after, _ := taskWithSortKey(field, cur.Key)
+		after.ID = cur.ID
+		start = sort.Search(len(tasks), func(i int) bool { return less(after, tasks[i]) })
+	}
+	end := start + limit
+	if end >= len(tasks) {
+		return tasks[start:], ""
+	}
+	page := tasks[start:end]
+	if len(page) == 0 {
+		return page, ""
+	}
+	return page, encodeCursor(sortSpec, page[len(page)-1])
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dryRunHeader marks a response to a ?dry_run=true request, which describes
+// the change the request would have made without making it.
+const dryRunHeader = "Dry-Run"
+
+type dryRunKey struct{}
+
+// withDryRun marks ctx so that changes made through as and undoing on it are
+// checked but not stored.
+func withDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+func isDryRun(ctx context.Context) bool {
+	dry, _ := ctx.Value(dryRunKey{}).(bool)
+	return dry
+}
+
+// parseDryRun reads the dry_run query parameter and, if it is set, marks the
+// request's context with withDryRun and the response with dryRunHeader. It
+// answers 400 itself on an invalid value and then returns false.
+func parseDryRun(c *gin.Context) (dryRun, ok bool) {
+	v, err := parseBoolQuery(c, "dry_run")
+	if err != nil {
+		respondError(c, 400, CodeInvalidRequest, err.Error())
+		return false, false
+	}
+	if v == nil || !*v {
+		return false, true
+	}
+	c.Request = c.Request.WithContext(withDryRun(c.Request.Context()))
+	c.Header(dryRunHeader, "true")
+	return true, true
+}
+
+// dryRunStore is a TaskRepository that reports the outcome each change would
+// have had on the repository it wraps, errors included, but leaves it as is.
+// It replaces auditingStore at the bottom of the chain built by undoing, so
+// the owner and limit checks above it still run and nothing is audited or
+// published.
+type dryRunStore struct {
+	TaskRepository
+}
+
+func (s *dryRunStore) Create(ctx context.Context, t Task) error {
+	return s.CreateMany(ctx, []Task{t})
+}
+
+func (s *dryRunStore) CreateMany(ctx context.Context, ts []Task) error {
+	seen := make(map[string]bool, len(ts))
+	for _, t := range ts {
+		_, found, err := s.Get(ctx, t.ID)
+		if err != nil {
+			return err
+		}
+		if found || seen[t.ID] {
+			return errDuplicateID
+		}
+		seen[t.ID] = true
+	}
+	return nil
+}
+
+func (s *dryRunStore) Update(ctx context.Context, id string, _ Task) (bool, error) {
+	_, found, err := s.Get(ctx, id)
+	return found, err
+}
+
+func (s *dryRunStore) Modify(ctx context.Context, id string, fn func(*Task) error) (Task, bool, error) {
+	t, found, err := s.Get(ctx, id)
+	if err != nil || !found {
+		return Task{}, found, err
+	}
+	if err := fn(&t); err != nil {
+		return Task{}, true, err
+	}
+	return t, true, nil
+}
+
+func (s *dryRunStore) ModifyMany(ctx context.Context, ids []string, fn func(*Task) error) ([]Task, []string, error) {
+	modified, missing := []Task{}, []string{}
+	for _, id := range ids {
+		t, found, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !found {
+			missing = append(missing, id)
+			continue
+		}
+		err = fn(&t)
+		if errors.Is(err, errSkip) {
+			missing = append(missing, id)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		modified = append(modified, t)
+	}
+	return modified, missing, nil
+}
+
+func (s *dryRunStore) Delete(ctx context.Context, id string) (bool, error) {
+	_, found, err := s.Get(ctx, id)
+	return found, err
+}
+
+func (s *dryRunStore) TrashCompleted(ctx context.Context, at time.Time) ([]Task, error) {
+	tasks, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	trashed := []Task{}
+	for _, t := range tasks {
+		if t.Done && t.DeletedAt == nil {
+			t.DeletedAt = &at
+			trashed = append(trashed, t)
+		}
+	}
+	return trashed, nil
+}
+
+func (s *dryRunStore) ReplaceAll(ctx context.Context, ts []Task) ([]Task, error) {
+	seen := make(map[string]bool, len(ts))
+	for _, t := range ts {
+		if seen[t.ID] {
+			return nil, errDuplicateID
+		}
+		seen[t.ID] = true
+	}
+	return s.List(ctx)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"mime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// envelopeProfile, as the profile parameter of an application/json Accept
+// type, asks for an enveloped list like ?envelope=true.
+const envelopeProfile = "envelope"
+
+// listEnvelope wraps a page of tasks with its paging metadata. Data holds
+// []Task, or the selected fields of each when ?fields= is given.
+type listEnvelope struct {
+	Data any      `json:"data"`
+	Meta listMeta `json:"meta"`
+}
+
+// listMeta repeats what the X-Total-Count, X-Limit, X-Offset and
+// X-Next-Cursor headers of a bare list say.
+type listMeta struct {
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// wantsEnvelope reports whether a list should be sent as a listEnvelope:
+// when ?envelope=true, or else an Accept type of application/json with
+// profile=envelope, asks for it. XML lists are never enveloped.
+func wantsEnvelope(c *gin.Context) (bool, error) {
+	if len(c.QueryArray("envelope")) > 1 {
+		return false, errors.New("envelope may only be given once")
+	}
+	envelope, err := parseBoolQuery(c, "envelope")
+	if err != nil || wantsXML(c) {
+		return false, err
+	}
+	if envelope != nil {
+		return *envelope, nil
+	}
+	for _, part := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err == nil && mediaType == "application/json" && params["profile"] == envelopeProfile {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// newListEnvelope wraps page, narrowed to fields if any are given.
+func newListEnvelope(page []Task, fields []string, meta listMeta) (listEnvelope, error) {
+	var data any = page
+	if len(fields) > 0 {
+		var err error
+		if data, err = selectFields(page, fields); err != nil {
+			return listEnvelope{}, err
+		}
+	}
+	return listEnvelope{Data: data, Meta: meta}, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errPreconditionFailed = errors.New("If-Match does not match the current task")
+
+// computeETag returns a strong ETag derived from the JSON encoding of v.
+func computeETag(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// xmlETag returns the ETag of the XML representation of the data etag was
+// computed for. A strong validator has to tell the encodings apart.
+func xmlETag(etag string) string {
+	return strings.TrimSuffix(etag, `"`) + `-xml"`
+}
+
+// etagMatches reports whether etag is listed in an If-Match or If-None-Match
+// header value. Weak validators are compared by their opaque tag only.
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch enforces an If-Match precondition against the stored task,
+// accepting the ETag of either its JSON or its XML representation. An empty
+// header always passes.
+func checkIfMatch(ifMatch string, stored Task) error {
+	if ifMatch == "" {
+		return nil
+	}
+	etag, err := computeETag(stored)
+	if err != nil {
+		return err
+	}
+	if !etagMatches(ifMatch, etag) && !etagMatches(ifMatch, xmlETag(etag)) {
+		return errPreconditionFailed
+	}
+	return nil
+}
+
+// respondWithETag writes v with an ETag header, or an empty 304 if the
+// client's If-None-Match already has this representation. v is sent as JSON
+// unless the client asked for XML, whose ETag is xmlETag's form of the JSON
+// one; both are accepted in If-Match. Non-empty fields reduce each JSON
+// task to those fields, which gives the response an ETag of its own; XML
+// always has every field.
+func respondWithETag(c *gin.Context, status int, v any, fields []string) {
+	xml := wantsXML(c)
+	if len(fields) > 0 && !xml {
+		var err error
+		if v, err = selectFields(v, fields); err != nil {
+			respondError(c, 500, CodeInternal, err.Error())
+			return
+		}
+	}
+	etag, err := computeETag(v)
+	if err != nil {
+		respondError(c, 500, CodeInternal, err.Error())
+		return
+	}
+	if xml {
+		etag = xmlETag(etag)
+	}
+	c.Header("ETag", etag)
+	c.Writer.Header().Add("Vary", "Accept")
+	if inm := c.GetHeader("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+		c.Status(304)
+		return
+	}
+	if xml {
+		c.XML(status, xmlBody(v))
+		return
+	}
+	c.JSON(status, v)
+}
+
+// setTaskETag sets the ETag header for a task returned by a write, so the
+// client can use it in a follow-up If-Match.
+func setTaskETag(c *gin.Context, t Task) {
+	if etag, err := computeETag(t); err == nil {
+		c.Header("ETag", etag)
+	}
+}
+
+// notModifiedSince sets the Last-Modified header to modified and reports
+// whether the client's If-Modified-Since is at or after it, in which case a
+// 304 will do. As with http.ServeContent the comparison is at the
+// one-second resolution of HTTP dates, and If-Modified-Since is ignored when
+// If-None-Match is sent, which takes precedence.
+func notModifiedSince(c *gin.Context, modified time.Time) bool {
+	c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	ims := c.GetHeader("If-Modified-Since")
+	if ims == "" || c.GetHeader("If-None-Match") != "" {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	return err == nil && !modified.Truncate(time.Second).After(since)
+}
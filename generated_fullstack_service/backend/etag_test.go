@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestETagDependsOnEncoding(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	task := createTask(t, srv, `{"title":"Tagged"}`)
+	path := "/v1/tasks/" + task.ID
+
+	jsonResp, _ := request(t, srv, "GET", path, "")
+	xmlResp, _ := request(t, srv, "GET", path, "", "Accept", "application/xml")
+	jsonTag, xmlTag := jsonResp.Header.Get("ETag"), xmlResp.Header.Get("ETag")
+	if jsonTag == "" || xmlTag == "" || jsonTag == xmlTag {
+		t.Fatalf("JSON ETag %q, XML ETag %q; want two different ones", jsonTag, xmlTag)
+	}
+	for name, resp := range map[string]*http.Response{"JSON": jsonResp, "XML": xmlResp} {
+		if vary := resp.Header.Values("Vary"); !slices.Contains(vary, "Accept") {
+			t.Errorf("%s Vary = %q, want it to list Accept", name, vary)
+		}
+	}
+
+	if resp, _ := request(t, srv, "GET", path, "", "Accept", "application/xml", "If-None-Match", jsonTag); resp.StatusCode != 200 {
+		t.Errorf("XML with the JSON ETag in If-None-Match: status %d, want 200", resp.StatusCode)
+	}
+	if resp, _ := request(t, srv, "GET", path, "", "Accept", "application/xml", "If-None-Match", xmlTag); resp.StatusCode != 304 {
+		t.Errorf("XML with the XML ETag in If-None-Match: status %d, want 304", resp.StatusCode)
+	}
+	if resp, _ := request(t, srv, "GET", "/v1/tasks", "", "Accept", "application/xml", "If-None-Match", jsonTag); resp.StatusCode != 200 {
+		t.Errorf("XML list with a task's ETag: status %d, want 200", resp.StatusCode)
+	}
+
+	if resp, b := request(t, srv, "PATCH", path, `{"done":true}`, "If-Match", xmlTag); resp.StatusCode != 200 {
+		t.Errorf("PATCH with the XML ETag in If-Match: status %d, want 200: %s", resp.StatusCode, b)
+	}
+	if resp, _ := request(t, srv, "PATCH", path, `{"done":false}`, "If-Match", xmlTag); resp.StatusCode != 412 {
+		t.Errorf("PATCH with a stale ETag: status %d, want 412", resp.StatusCode)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types published when tasks change. Restoring a task from the trash
+// is reported as created, since it reappears in the active list.
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind by before
+// it is disconnected.
+const subscriberBuffer = 64
+
+// TaskEvent describes one change to a task. Task is the state after the
+// change; for hard deletes only its ID is set. ID and At are set by Publish
+// and only sent in the CloudEvents format.
+type TaskEvent struct {
+	Type string    `json:"type"`
+	Task Task      `json:"task"`
+	ID   string    `json:"-"`
+	At   time.Time `json:"-"`
+}
+
+// EventBroker fans task events out to subscribers.
+type EventBroker struct {
+	mu   sync.Mutex
+	subs map[chan TaskEvent]struct{}
+	// lastModified is when the latest event was published, or the broker
+	// created if none has been.
+	lastModified time.Time
+}
+
+func NewEventBroker() *EventBroker {
+	return &EventBroker{subs: make(map[chan TaskEvent]struct{}), lastModified: time.Now()}
+}
+
+// LastModified returns when a task last changed, as far as this broker has
+// seen: every change made through a PublishingStore, deletes included,
+// publishes an event. Until the first one it is when the broker was created,
+// since changes made before then are unknown.
+func (b *EventBroker) LastModified() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastModified
+}
+
+// Subscribe returns a channel of future events and a function that ends the
+// subscription. The channel is closed when the subscription ends, including
+// when the subscriber falls too far behind.
+func (b *EventBroker) Subscribe() (<-chan TaskEvent, func()) {
+	ch := make(chan TaskEvent, subscriberBuffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(ch)
+	}
+}
+
+// Publish delivers ev to every subscriber without blocking. A subscriber
+// whose buffer is full is dropped rather than silently missing events.
+func (b *EventBroker) Publish(ev TaskEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastModified = time.Now()
+	ev.ID, ev.At = uuid.NewString(), b.lastModified.UTC()
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			b.remove(ch)
+		}
+	}
+}
+
+// Close ends every current subscription, which lets open streams finish
+// during shutdown.
+func (b *EventBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		b.remove(ch)
+	}
+}
+
+// remove ends a subscription. The caller must hold b.mu.
+func (b *EventBroker) remove(ch chan TaskEvent) {
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// PublishingStore is a TaskRepository decorator that publishes an event for
+// every successful change made through it.
+type PublishingStore struct {
+	TaskRepository
+	events *EventBroker
+}
+
+func NewPublishingStore(repo TaskRepository, events *EventBroker) *PublishingStore {
+	return &PublishingStore{TaskRepository: repo, events: events}
+}
+
+func (s *PublishingStore) Create(ctx context.Context, t Task) error {
+	if err := s.TaskRepository.Create(ctx, t); err != nil {
+		return err
+	}
+	s.events.Publish(TaskEvent{Type: EventCreated, Task: t})
+	return nil
+}
+
+func (s *PublishingStore) CreateMany(ctx context.Context, ts []Task) error {
+	if err := s.TaskRepository.CreateMany(ctx, ts); err != nil {
+		return err
+	}
+	for _, t := range ts {
+		s.events.Publish(TaskEvent{Type: EventCreated, Task: t})
+	}
+	return nil
+}
+
+func (s *PublishingStore) Update(ctx context.Context, id string, t Task) (bool, error) {
+	found, err := s.TaskRepository.Update(ctx, id, t)
+	if err == nil && found {
+		s.events.Publish(TaskEvent{Type: EventUpdated, Task: t})
+	}
+	return found, err
+}
+
+// Modify classifies the change by comparing the trash state before and after
+// fn, so soft deletes and restores are reported as such.
+func (s *PublishingStore) Modify(ctx context.Context, id string, fn func(*Task) error) (Task, bool, error) {
+	var wasTrashed bool
+	t, found, err := s.TaskRepository.Modify(ctx, id, func(t *Task) error {
+		wasTrashed = t.DeletedAt != nil
+		return fn(t)
+	})
+	if err != nil || !found {
+		return t, found, err
+	}
+	s.events.Publish(TaskEvent{Type: modifyEventType(wasTrashed, t), Task: t})
+	return t, true, nil
+}
+
+func (s *PublishingStore) ModifyMany(ctx context.Context, ids []string, fn func(*Task) error) ([]Task, []string, error) {
+	wasTrashed := map[string]bool{}
+	modified, missing, err := s.TaskRepository.ModifyMany(ctx, ids, func(t *Task) error {
+		wasTrashed[t.ID] = t.DeletedAt != nil
+		return fn(t)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, t := range modified {
+		s.events.Publish(TaskEvent{Type: modifyEventType(wasTrashed[t.ID], t), Task: t})
+	}
+	return modified, missing, nil
+}
+
+// modifyEventType reports a modified task as deleted or created when it
+// was moved into or out of the trash, and as updated otherwise.
+func modifyEventType(wasTrashed bool, t Task) string {
+	switch isTrashed := t.DeletedAt != nil; {
+	case isTrashed && !wasTrashed:
+		return EventDeleted
+	case !isTrashed && wasTrashed:
+		return EventCreated
+	}
+	return EventUpdated
+}
+
+func (s *PublishingStore) Delete(ctx context.Context, id string) (bool, error) {
+	found, err := s.TaskRepository.Delete(ctx, id)
+	if err == nil && found {
+		s.events.Publish(TaskEvent{Type: EventDeleted, Task: Task{ID: id}})
+	}
+	return found, err
+}
+
+func (s *PublishingStore) TrashCompleted(ctx context.Context, at time.Time) ([]Task, error) {
+	trashed, err := s.TaskRepository.TrashCompleted(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range trashed {
+		s.events.Publish(TaskEvent{Type: EventDeleted, Task: t})
+	}
+	return trashed, nil
+}
+
+// ReplaceAll reports every removed task as hard deleted, then every active
+// task put in their place as created.
+func (s *PublishingStore) ReplaceAll(ctx context.Context, ts []Task) ([]Task, error) {
+	removed, err := s.TaskRepository.ReplaceAll(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range removed {
+		s.events.Publish(TaskEvent{Type: EventDeleted, Task: Task{ID: t.ID}})
+	}
+	for _, t := range ts {
+		if t.DeletedAt == nil {
+			s.events.Publish(TaskEvent{Type: EventCreated, Task: t})
+		}
+	}
+	return removed, nil
+}
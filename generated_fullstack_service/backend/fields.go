@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// taskFieldNames holds the JSON name of every Task field that ?fields= can
+// select, derived from the struct so it grows with it.
+var taskFieldNames = jsonFieldNames(reflect.TypeOf(Task{}))
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name != "-" && f.IsExported() {
+			if name == "" {
+				name = f.Name
+			}
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// parseFields reads the comma-separated ?fields= parameter. A nil result
+// means every field; an unknown name is an error.
+func parseFields(c *gin.Context) ([]string, error) {
+	v := c.Query("fields")
+	if v == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, f := range strings.Split(v, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !taskFieldNames[f] {
+			return nil, fmt.Errorf("unknown field %q", f)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// selectFields returns v, a task or a slice of tasks, with each task reduced
+// to the given fields. It works on the JSON encoding, so the result matches
+// what v would have been sent as.
+func selectFields(v any, fields []string) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded any
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return nil, err
+	}
+	switch d := decoded.(type) {
+	case []any:
+		for _, item := range d {
+			if obj, ok := item.(map[string]any); ok {
+				pruneFields(obj, fields)
+			}
+		}
+	case map[string]any:
+		pruneFields(d, fields)
+	}
+	return decoded, nil
+}
+
+func pruneFields(obj map[string]any, fields []string) {
+	keep := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		keep[f] = true
+	}
+	for k := range obj {
+		if !keep[k] {
+			delete(obj, k)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"time"
+	// The zone database is built in, so ?tz= works on hosts without one.
+	_ "time/tzdata"
+
+	"github.com/gin-gonic/gin"
+)
+
+// taskFilter holds the filter parameters of GET /tasks. Each parameter that is
+// given is one condition: a repeated priority or owner matches a task with any
+// of the values, a repeated tag one carrying every tag, or any of them under
+// match=any. Conditions on different parameters must all hold, or under
+// match=any at least one.
+//
+// With a zone, days are those of the zone: overdue then means not done and
+// due before the zone's last midnight, and due=today due between it and the
+// next. Without one, overdue compares the due date with the current instant
+// and due=today uses the UTC day.
+type taskFilter struct {
+	matchAny   bool
+	done       *bool
+	overdue    *bool
+	dueToday   bool
+	zone       *time.Location
+	query      string
+	priorities []string
+	owners     []string
+	tags       []string
+}
+
+// parseTaskFilter reads the filter parameters of c. Single-valued parameters
+// may only be given once, and match only when there is something to combine.
+func parseTaskFilter(c *gin.Context) (taskFilter, error) {
+	var f taskFilter
+	var err error
+	for _, name := range []string{"done", "overdue", "due", "tz", "q", "match"} {
+		if len(c.QueryArray(name)) > 1 {
+			return f, errors.New(name + " may only be given once")
+		}
+	}
+	if f.done, err = parseBoolQuery(c, "done"); err != nil {
+		return f, err
+	}
+	if f.overdue, err = parseBoolQuery(c, "overdue"); err != nil {
+		return f, err
+	}
+	if due, ok := c.GetQuery("due"); ok {
+		if due != "today" {
+			return f, errors.New("due must be today")
+		}
+		f.dueToday = true
+	}
+	if tz, ok := c.GetQuery("tz"); ok {
+		if f.zone, err = loadZone(tz); err != nil {
+			return f, err
+		}
+		if f.overdue == nil && !f.dueToday {
+			return f, errors.New("tz needs overdue or due to apply to")
+		}
+	}
+	f.query = c.Query("q")
+	for _, p := range c.QueryArray("priority") {
+		if _, ok := priorityRank[p]; !ok {
+			return f, errors.New("priority must be one of low, medium, high")
+		}
+	}
+	f.priorities = nonEmpty(c.QueryArray("priority"))
+	f.owners = nonEmpty(c.QueryArray("owner"))
+	f.tags = c.QueryArray("tag")
+	if match, ok := c.GetQuery("match"); ok {
+		switch match {
+		case "all":
+		case "any":
+			f.matchAny = true
+		default:
+			return f, errors.New("match must be any or all")
+		}
+		if len(f.conditions(time.Time{})) == 0 {
+			return f, errors.New("match needs at least one filter to combine")
+		}
+	}
+	return f, nil
+}
+
+// loadZone returns the IANA time zone named tz. The server's own zone, Local,
+// is refused, as is the empty name.
+func loadZone(tz string) (*time.Location, error) {
+	if tz == "" || tz == "Local" {
+		return nil, errors.New("tz must be an IANA time zone name such as America/New_York")
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, errors.New("tz must be an IANA time zone name such as America/New_York")
+	}
+	return loc, nil
+}
+
+// today returns the bounds of the day containing now in the filter's zone,
+// or in UTC without one. The next midnight is found by date, so days across a
+// daylight saving change come out as 23 or 25 hours.
+func (f taskFilter) today(now time.Time) (start, end time.Time) {
+	zone := f.zone
+	if zone == nil {
+		zone = time.UTC
+	}
+	y, m, d := now.In(zone).Date()
+	start = time.Date(y, m, d, 0, 0, 0, 0, zone)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// nonEmpty drops empty values, so ?owner= filters nothing as it always has.
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// conditions returns one test per parameter that is set, in a fixed order.
+func (f taskFilter) conditions(now time.Time) []func(Task) bool {
+	var conds []func(Task) bool
+	if f.done != nil {
+		conds = append(conds, func(t Task) bool { return t.Done == *f.done })
+	}
+	if len(f.priorities) > 0 {
+		conds = append(conds, func(t Task) bool { return containsString(f.priorities, t.Priority) })
+	}
+	if len(f.owners) > 0 {
+		conds = append(conds, func(t Task) bool { return containsString(f.owners, t.Owner) })
+	}
+	if len(f.tags) > 0 {
+		tagged := make([]func(Task) bool, len(f.tags))
+		for i, tag := range f.tags {
+			tag := tag
+			tagged[i] = func(t Task) bool { return hasTag(t, tag) }
+		}
+		conds = append(conds, combine(tagged, f.matchAny))
+	}
+	start, end := f.today(now)
+	if f.overdue != nil {
+		cutoff := now
+		if f.zone != nil {
+			cutoff = start
+		}
+		conds = append(conds, func(t Task) bool { return isOverdue(t, cutoff) == *f.overdue })
+	}
+	if f.dueToday {
+		conds = append(conds, func(t Task) bool {
+			return t.DueDate != nil && !t.DueDate.Before(start) && t.DueDate.Before(end)
+		})
+	}
+	if f.query != "" {
+		conds = append(conds, func(t Task) bool { return matchesQuery(t, f.query) })
+	}
+	return conds
+}
+
+// predicate combines the conditions into a single test, evaluated against now
+// for overdue and due. With no conditions every task matches.
+func (f taskFilter) predicate(now time.Time) func(Task) bool {
+	conds := f.conditions(now)
+	if len(conds) == 0 {
+		return func(Task) bool { return true }
+	}
+	return combine(conds, f.matchAny)
+}
+
+// combine returns a test passed when every one of conds is, or with matchAny set
+// when at least one is.
+func combine(conds []func(Task) bool, matchAny bool) func(Task) bool {
+	return func(t Task) bool {
+		for _, cond := range conds {
+			if cond(t) == matchAny {
+				return matchAny
+			}
+		}
+		return !matchAny
+	}
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
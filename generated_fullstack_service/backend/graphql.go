@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+const graphQLPath = "/graphql"
+
+// graphQLSubjectKey is the context key the resolvers read the caller's
+// subject from.
+type graphQLSubjectKey struct{}
+
+// graphQLRequest is a GraphQL-over-HTTP POST body.
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// graphQLError is a resolver error carrying the same code and status the
+// REST route would have answered with, under "extensions".
+type graphQLError struct {
+	status int
+	code   string
+	msg    string
+}
+
+func (e *graphQLError) Error() string { return e.msg }
+
+func (e *graphQLError) Extensions() map[string]any {
+	return map[string]any{"code": e.code, "status": e.status}
+}
+
+// toGraphQLError gives an error from the shared task paths its REST code.
+func toGraphQLError(err error) error {
+	status, code := modifyErrorStatus(err)
+	return &graphQLError{status: status, code: code, msg: err.Error()}
+}
+
+var errGraphQLTaskNotFound = &graphQLError{status: 404, code: CodeTaskNotFound, msg: "task not found"}
+
+// registerGraphQL serves POST /graphql behind the given middleware, and with
+// playground set, a GraphiQL page on GET /graphql.
+func (h *TaskHandler) registerGraphQL(r gin.IRouter, playground bool, middleware ...gin.HandlerFunc) {
+	schema, err := h.graphQLSchema()
+	if err != nil {
+		// The schema is fixed, so this is a programming error.
+		panic("graphql: " + err.Error())
+	}
+	r.POST(graphQLPath, append(middleware, func(c *gin.Context) {
+		var req graphQLRequest
+		if err := bindJSON(c, &req); err != nil {
+			respondDecodeError(c, err)
+			return
+		}
+		if isGraphQLMutation(req) && refuseReadOnly(c, h.readOnly) {
+			return
+		}
+		ctx := context.WithValue(c.Request.Context(), graphQLSubjectKey{}, c.GetString(subjectKey))
+		c.JSON(200, graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  req.Query,
+			OperationName:  req.OperationName,
+			VariableValues: req.Variables,
+			Context:        ctx,
+		}))
+	})...)
+	if playground {
+		r.GET(graphQLPath, func(c *gin.Context) {
+			c.Data(200, "text/html; charset=utf-8", []byte(graphiQLPage))
+		})
+	}
+}
+
+// isGraphQLMutation reports whether req runs a mutation: the operation it
+// names, or any of them if it names none. A query that doesn't parse runs
+// nothing, and is left to graphql.Do to report.
+func isGraphQLMutation(req graphQLRequest) bool {
+	doc, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err != nil {
+		return false
+	}
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok || op.Operation != ast.OperationTypeMutation {
+			continue
+		}
+		if req.OperationName == "" || (op.Name != nil && op.Name.Value == req.OperationName) {
+			return true
+		}
+	}
+	return false
+}
+
+// graphQLSchema exposes tasks over GraphQL. Field names match the REST JSON,
+// and every resolver goes through the same paths as the REST handlers, so
+// validation, auditing and events are the same.
+func (h *TaskHandler) graphQLSchema() (graphql.Schema, error) {
+	nonNullString := graphql.NewNonNull(graphql.String)
+	stringList := graphql.NewNonNull(graphql.NewList(nonNullString))
+
+	subtaskType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subtask",
+		Fields: graphql.Fields{
+			"id":    {Type: graphql.NewNonNull(graphql.ID)},
+			"title": {Type: nonNullString},
+			"done":  {Type: graphql.NewNonNull(graphql.Boolean)},
+		},
+	})
+	commentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Comment",
+		Fields: graphql.Fields{
+			"id":         {Type: graphql.NewNonNull(graphql.ID)},
+			"author":     {Type: nonNullString},
+			"body":       {Type: nonNullString},
+			"created_at": {Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+	taskFields := graphql.Fields{
+		"id":              {Type: graphql.NewNonNull(graphql.ID)},
+		"title":           {Type: nonNullString},
+		"done":            {Type: graphql.NewNonNull(graphql.Boolean)},
+		"priority":        {Type: nonNullString},
+		"due_date":        {Type: graphql.DateTime},
+		"created_at":      {Type: graphql.NewNonNull(graphql.DateTime)},
+		"updated_at":      {Type: graphql.NewNonNull(graphql.DateTime)},
+		"tags":            {Type: stringList},
+		"subtasks":        {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(subtaskType)))},
+		"comments":        {Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(commentType)))},
+		"recurrence":      {Type: nonNullString},
+		"completion_mode": {Type: nonNullString},
+		"archived":        {Type: graphql.NewNonNull(graphql.Boolean)},
+		"owner":           {Type: nonNullString},
+		"parent_id":       {Type: graphql.String},
+		"order":           {Type: graphql.NewNonNull(graphql.Int)},
+		"version":         {Type: graphql.NewNonNull(graphql.Int)},
+	}
+	taskType := graphql.NewObject(graphql.ObjectConfig{Name: "Task", Fields: taskFields})
+	// CreatedTask is the task createTask returns, with what POST /v1/tasks
+	// says about duplicates alongside. Its source is a graphQLCreated, so
+	// the task fields are read from the Task inside.
+	createdFields := graphql.Fields{
+		"existing": {
+			Type:        graphql.NewNonNull(graphql.Boolean),
+			Description: "true when DEDUP returned an open task with the same title instead of creating one",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(graphQLCreated).existing, nil
+			},
+		},
+		"duplicate_of": {
+			Type:        graphql.String,
+			Description: "Under DUPLICATE_WARNING, the id of an open task with the same title",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				if id := p.Source.(graphQLCreated).duplicateOf; id != "" {
+					return id, nil
+				}
+				return nil, nil
+			},
+		},
+	}
+	for name, field := range taskFields {
+		createdFields[name] = &graphql.Field{Type: field.Type, Resolve: func(p graphql.ResolveParams) (any, error) {
+			p.Source = p.Source.(graphQLCreated).task
+			return graphql.DefaultResolveFn(p)
+		}}
+	}
+	createdTaskType := graphql.NewObject(graphql.ObjectConfig{Name: "CreatedTask", Fields: createdFields})
+
+	// The inputs accept what POST and PATCH /tasks do.
+	taskInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "TaskInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":           {Type: nonNullString},
+			"done":            {Type: graphql.Boolean},
+			"priority":        {Type: graphql.String},
+			"due_date":        {Type: graphql.DateTime},
+			"tags":            {Type: graphql.NewList(nonNullString)},
+			"recurrence":      {Type: graphql.String},
+			"completion_mode": {Type: graphql.String},
+			"owner":           {Type: graphql.String},
+		},
+	})
+	taskPatchInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "TaskPatchInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":    {Type: graphql.String},
+			"done":     {Type: graphql.Boolean},
+			"priority": {Type: graphql.String},
+			"due_date": {Type: graphql.DateTime},
+			// graphql-go drops null input values, so clearing the due
+			// date needs a field of its own.
+			"clear_due_date":  {Type: graphql.Boolean, Description: "true removes the due date"},
+			"tags":            {Type: graphql.NewList(nonNullString)},
+			"recurrence":      {Type: graphql.String},
+			"completion_mode": {Type: graphql.String},
+			"owner":           {Type: graphql.String},
+			"version":         {Type: graphql.Int, Description: "Must match the stored version if set"},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"tasks": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(taskType))),
+				Description: "Active tasks, filtered, sorted and paged like GET /v1/tasks",
+				Args: graphql.FieldConfigArgument{
+					"done":     {Type: graphql.Boolean},
+					"q":        {Type: graphql.String},
+					"priority": {Type: graphql.String},
+					"owner":    {Type: graphql.String},
+					"tags":     {Type: graphql.NewList(nonNullString)},
+					"sort":     {Type: graphql.String},
+					"limit":    {Type: graphql.Int, DefaultValue: h.pageLimit},
+					"offset":   {Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: h.resolveTasks,
+			},
+			"task": {
+				Type: taskType,
+				Args: graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					t, found, err := h.repo.Get(p.Context, p.Args["id"].(string))
+					if err != nil {
+						return nil, err
+					}
+					if !found || t.DeletedAt != nil {
+						return nil, nil
+					}
+					return t, nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createTask": {
+				Type: graphql.NewNonNull(createdTaskType),
+				Args: graphql.FieldConfigArgument{"input": {Type: graphql.NewNonNull(taskInput)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					var task Task
+					if err := decodeGraphQLInput(p.Args["input"], &task); err != nil {
+						return nil, err
+					}
+					applyDefaults(&task)
+					if err := validateTask(task); err != nil {
+						return nil, toGraphQLError(err)
+					}
+					ctx, subject := p.Context, graphQLSubject(p.Context)
+					created, existing, duplicateOf, err := h.createDeduped(ctx, subject, task, h.dedup, func() (Task, error) {
+						return h.createTask(ctx, subject, task)
+					})
+					if err != nil {
+						return nil, toGraphQLError(err)
+					}
+					return graphQLCreated{task: created, existing: existing, duplicateOf: duplicateOf}, nil
+				},
+			},
+			"updateTask": {
+				Type: graphql.NewNonNull(taskType),
+				Args: graphql.FieldConfigArgument{
+					"id":    {Type: graphql.NewNonNull(graphql.ID)},
+					"input": {Type: graphql.NewNonNull(taskPatchInput)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					input := p.Args["input"].(map[string]any)
+					if clear, _ := input["clear_due_date"].(bool); clear {
+						if _, ok := input["due_date"]; ok {
+							return nil, &graphQLError{status: 400, code: CodeInvalidRequest, msg: "due_date and clear_due_date cannot be combined"}
+						}
+						input["due_date"] = nil
+					}
+					delete(input, "clear_due_date")
+					var patch TaskPatch
+					if err := decodeGraphQLInput(input, &patch); err != nil {
+						return nil, err
+					}
+					task, found, err := h.patchTask(p.Context, graphQLSubject(p.Context), p.Args["id"].(string), patch, "")
+					if err != nil {
+						return nil, toGraphQLError(err)
+					}
+					if !found {
+						return nil, errGraphQLTaskNotFound
+					}
+					return task, nil
+				},
+			},
+			"deleteTask": {
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Moves the task to the trash, like DELETE /v1/tasks/{id}",
+				Args:        graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					found, err := h.trashTask(p.Context, graphQLSubject(p.Context), p.Args["id"].(string))
+					if err != nil {
+						return nil, toGraphQLError(err)
+					}
+					if !found {
+						return nil, errGraphQLTaskNotFound
+					}
+					return true, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// graphQLCreated is the result of createTask, see createDeduped.
+type graphQLCreated struct {
+	task        Task
+	existing    bool
+	duplicateOf string
+}
+
+func (h *TaskHandler) resolveTasks(p graphql.ResolveParams) (any, error) {
+	opts := listOptions{limit: p.Args["limit"].(int), offset: p.Args["offset"].(int)}
+	if opts.limit < 0 || opts.limit > maxPageLimit || opts.offset < 0 {
+		return nil, &graphQLError{status: 400, code: CodeInvalidRequest, msg: "limit must be between 0 and 100 and offset must not be negative"}
+	}
+	if done, ok := p.Args["done"].(bool); ok {
+		opts.filter.done = &done
+	}
+	opts.filter.query, _ = p.Args["q"].(string)
+	if owner, _ := p.Args["owner"].(string); owner != "" {
+		opts.filter.owners = []string{owner}
+	}
+	if priority, _ := p.Args["priority"].(string); priority != "" {
+		if _, ok := priorityRank[priority]; !ok {
+			return nil, &graphQLError{status: 400, code: CodeInvalidRequest, msg: "priority must be one of low, medium, high"}
+		}
+		opts.filter.priorities = []string{priority}
+	}
+	if tags, ok := p.Args["tags"].([]any); ok {
+		for _, tag := range tags {
+			opts.filter.tags = append(opts.filter.tags, tag.(string))
+		}
+	}
+	if sort, _ := p.Args["sort"].(string); sort != "" {
+		opts.sortField = sort
+		if sort[0] == '-' {
+			opts.sortField, opts.sortDesc = sort[1:], true
+		}
+		if _, ok := taskLess[opts.sortField]; !ok {
+			return nil, &graphQLError{status: 400, code: CodeInvalidRequest, msg: "cannot sort by " + opts.sortField}
+		}
+	}
+	tasks, err := h.repo.List(p.Context)
+	if err != nil {
+		return nil, err
+	}
+	page, _, _ := opts.apply(tasks)
+	return page, nil
+}
+
+// decodeGraphQLInput fills v, a Task or TaskPatch, from an input object by
+// way of its JSON form, so GraphQL input is read exactly like a REST body.
+func decodeGraphQLInput(input any, v any) error {
+	b, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return &graphQLError{status: 400, code: CodeInvalidRequest, msg: describeBindError(err).Error()}
+	}
+	return nil
+}
+
+func graphQLSubject(ctx context.Context) string {
+	s, _ := ctx.Value(graphQLSubjectKey{}).(string)
+	return s
+}
+
+const graphiQLPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Task Service GraphQL</title>
+  <link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css">
+</head>
+<body style="margin: 0">
+  <div id="graphiql" style="height: 100vh"></div>
+  <script src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+  <script src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+  <script src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+  <script>
+    const fetcher = GraphiQL.createFetcher({url: "/graphql"});
+    ReactDOM.createRoot(document.getElementById("graphiql")).render(React.createElement(GraphiQL, {fetcher}));
+  </script>
+</body>
+</html>`
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type graphQLCreatedTask struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Existing    bool    `json:"existing"`
+	DuplicateOf *string `json:"duplicate_of"`
+}
+
+func graphQLCreate(t *testing.T, srv *httptest.Server, title string) graphQLCreatedTask {
+	t.Helper()
+	query := `{"query":"mutation { createTask(input: {title: \"` + title + `\"}) { id title existing duplicate_of } }"}`
+	resp, b := request(t, srv, "POST", graphQLPath, query)
+	if resp.StatusCode != 200 || strings.Contains(string(b), `"errors"`) {
+		t.Fatalf("createTask %q: status %d: %s", title, resp.StatusCode, b)
+	}
+	return decode[struct {
+		Data struct {
+			CreateTask graphQLCreatedTask `json:"createTask"`
+		} `json:"data"`
+	}](t, b).Data.CreateTask
+}
+
+func TestGraphQLCreateDedup(t *testing.T) {
+	cfg := testConfig()
+	cfg.Dedup = true
+	srv := newTestServer(t, cfg)
+
+	first := graphQLCreate(t, srv, "Buy milk")
+	again := graphQLCreate(t, srv, "  buy MILK ")
+	if first.Existing {
+		t.Errorf("first create: existing, want a new task")
+	}
+	if !again.Existing || again.ID != first.ID {
+		t.Errorf("second create: existing %v, id %s; want the first task %s", again.Existing, again.ID, first.ID)
+	}
+}
+
+func TestGraphQLCreateDuplicateWarning(t *testing.T) {
+	cfg := testConfig()
+	cfg.DuplicateWarning = true
+	srv := newTestServer(t, cfg)
+
+	first := graphQLCreate(t, srv, "Buy milk")
+	again := graphQLCreate(t, srv, "Buy milk")
+	if first.DuplicateOf != nil {
+		t.Errorf("first create: duplicate_of %q, want null", *first.DuplicateOf)
+	}
+	if again.Existing || again.ID == first.ID || again.DuplicateOf == nil || *again.DuplicateOf != first.ID {
+		t.Errorf("second create: %+v, want a new task with duplicate_of %s", again, first.ID)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"compress/gzip"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gzipMinSize is the smallest response worth compressing; below it the gzip
+// framing overhead outweighs the savings.
+const gzipMinSize = 1024
+
+var gzipWriterPool = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+// Gzip compresses response bodies of at least minSize bytes for clients that
+// accept gzip. Requests to the excluded paths, and responses that already set
+// a Content-Encoding, pass through untouched.
+func Gzip(minSize int, excludedPaths ...string) gin.HandlerFunc {
+	excluded := make(map[string]bool, len(excludedPaths))
+	for _, p := range excludedPaths {
+		excluded[p] = true
+	}
+
+	return func(c *gin.Context) {
+		if excluded[c.Request.URL.Path] || !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		w := &gzipResponseWriter{ResponseWriter: c.Writer, minSize: minSize}
+		c.Writer = w
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		defer func() {
+			w.finish()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if strings.TrimSpace(coding) != "gzip" {
+			continue
+		}
+		v, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		q, err := strconv.ParseFloat(v, 64)
+		return err == nil && q > 0
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response until it knows whether
+// the body is large enough to compress.
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	minSize int
+	buf     []byte
+	gz      *gzip.Writer
+	// passthrough is set once the response is committed to being sent
+	// uncompressed.
+	passthrough bool
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	switch {
+	case w.gz != nil:
+		return w.gz.Write(b)
+	case w.passthrough:
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) < w.minSize {
+		return len(b), nil
+	}
+	if w.Header().Get("Content-Encoding") != "" {
+		w.passthrough = true
+		return len(b), w.flushBuffer(w.ResponseWriter.Write)
+	}
+
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Encoding", "gzip")
+	w.gz = gzipWriterPool.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+	return len(b), w.flushBuffer(w.gz.Write)
+}
+
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what has been written so far. A response flushed before it
+// reached minSize is streamed uncompressed from then on.
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	} else if !w.passthrough {
+		w.passthrough = true
+		w.flushBuffer(w.ResponseWriter.Write)
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *gzipResponseWriter) flushBuffer(write func([]byte) (int, error)) error {
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := write(buf)
+	return err
+}
+
+// finish writes out any buffered bytes and closes the gzip stream.
+func (w *gzipResponseWriter) finish() {
+	if w.gz != nil {
+		w.gz.Close()
+		gzipWriterPool.Put(w.gz)
+		w.gz = nil
+		return
+	}
+	w.flushBuffer(w.ResponseWriter.Write)
+}
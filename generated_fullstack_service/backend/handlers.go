@@ -0,0 +1,694 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errNotTrashed = errors.New("task is not in the trash")
+
+// errUnchanged is returned by a Modify callback whose change would leave the
+// task as it was, so that nothing is stored, audited or broadcast.
+var errUnchanged = errors.New("task unchanged")
+
+// TaskHandler serves the /tasks endpoints on top of a TaskRepository.
+type TaskHandler struct {
+	// repo is for reads, which it traces; changes go through as so that
+	// they are audited.
+	repo           TaskRepository
+	audit          AuditLog
+	events         *EventBroker
+	idempotency    *IdempotencyCache
+	importMaxBytes int64
+	// pageLimit is the page size of lists requested without a limit.
+	pageLimit int
+	// ownerOnlyWrites limits changes to a task to its owner.
+	ownerOnlyWrites bool
+	// dedup makes Create return an open task with the same title instead
+	// of adding another; ?dedup= overrides it per request. dedupMu makes
+	// the check and the create one step.
+	dedup   bool
+	dedupMu sync.Mutex
+	// warnDuplicates makes Create point out such a task, still creating
+	// the new one.
+	warnDuplicates bool
+	// undoMu serialises Undo.
+	undoMu sync.Mutex
+	// moveMu serialises Move, which renumbers tasks based on a List.
+	moveMu sync.Mutex
+	// maxTasks caps the number of active tasks; 0 means no limit. createMu
+	// serialises creates while it is set.
+	maxTasks int
+	createMu sync.Mutex
+	// readOnly refuses WebSocket commands as ReadOnly refuses HTTP writes.
+	readOnly *ReadOnlyMode
+	// eventFormat is how events are sent to stream and WebSocket clients,
+	// EventFormatRaw or EventFormatCloudEvents.
+	eventFormat string
+}
+
+// NewTaskHandler wraps store so every change it makes is published to the
+// handler's event stream and recorded in its audit log.
+func NewTaskHandler(store Store) *TaskHandler {
+	events := NewEventBroker()
+	return &TaskHandler{
+		repo:           &tracingStore{NewPublishingStore(store, events)},
+		audit:          store,
+		events:         events,
+		idempotency:    NewIdempotencyCache(idempotencyTTL),
+		importMaxBytes: defaultImportMaxBytes,
+		pageLimit:      defaultPageLimit,
+		eventFormat:    EventFormatRaw,
+	}
+}
+
+// RegisterRoutes mounts the read endpoints on reads and the mutating ones on
+// writes, which lets the caller put authentication in front of writes only.
+func (h *TaskHandler) RegisterRoutes(reads, writes gin.IRoutes) {
+	getAndHead(reads, "/tasks", h.List)
+	getAndHead(reads, "/tasks.csv", h.ExportCSV)
+	getAndHead(reads, "/tasks/trash", h.ListTrash)
+	getAndHead(reads, "/tasks/stats", h.Stats)
+	getAndHead(reads, "/tasks/export", h.ExportSnapshot)
+	// A HEAD on the stream would never finish.
+	reads.GET("/tasks/stream", h.Stream)
+	getAndHead(reads, "/tasks/:id", h.Get)
+	getAndHead(reads, "/tasks/:id/comments", h.ListComments)
+	getAndHead(reads, "/tasks/:id/history", h.History)
+
+	writes.POST("/tasks", h.Create)
+	writes.POST("/tasks/bulk", h.CreateBulk)
+	writes.POST("/tasks/import", h.ImportCSV)
+	writes.POST("/tasks/batch", h.BatchUpdate)
+	writes.POST("/tasks/undo", h.Undo)
+	writes.POST("/tasks/restore", h.RestoreSnapshot)
+	writes.PUT("/tasks/:id", h.Replace)
+	writes.PATCH("/tasks/:id", h.Patch)
+	writes.DELETE("/tasks/completed", h.DeleteCompleted)
+	writes.DELETE("/tasks/:id", h.Delete)
+	writes.POST("/tasks/:id/restore", h.Restore)
+	writes.POST("/tasks/:id/toggle", h.Toggle)
+	writes.POST("/tasks/:id/move", h.Move)
+	writes.POST("/tasks/:id/subtasks", h.CreateSubtask)
+	writes.PUT("/tasks/:id/subtasks/:subId", h.UpdateSubtask)
+	writes.DELETE("/tasks/:id/subtasks/:subId", h.DeleteSubtask)
+	writes.POST("/tasks/:id/comments", h.CreateComment)
+	writes.DELETE("/tasks/:id/comments/:commentId", h.DeleteComment)
+	// The socket accepts commands as well as pushing events, so it sits
+	// behind the same authentication as the other writes.
+	writes.GET("/ws", h.WebSocket)
+	// The audit log names who changed what, so it isn't public either.
+	writes.GET("/audit", h.ListAudit)
+}
+
+func (h *TaskHandler) List(c *gin.Context) {
+	switch c.Query("format") {
+	case "csv":
+		h.ExportCSV(c)
+		return
+	case "ndjson":
+		h.ExportNDJSON(c)
+		return
+	}
+	h.list(c, false)
+}
+
+func (h *TaskHandler) ListTrash(c *gin.Context) {
+	h.list(c, true)
+}
+
+func (h *TaskHandler) list(c *gin.Context, trashed bool) {
+	opts, err := parseListOptions(c, h.pageLimit)
+	if err != nil {
+		respondError(c, 400, CodeInvalidRequest, err.Error())
+		return
+	}
+	opts.trashed = trashed
+	fields, err := parseFields(c)
+	if err != nil {
+		respondError(c, 400, CodeInvalidRequest, err.Error())
+		return
+	}
+	envelope, err := wantsEnvelope(c)
+	if err != nil {
+		respondError(c, 400, CodeInvalidRequest, err.Error())
+		return
+	}
+	// Read before listing, so the list is at least as new as the header
+	// claims.
+	if notModifiedSince(c, h.events.LastModified()) {
+		c.Status(304)
+		return
+	}
+	tasks, err := h.repo.List(c.Request.Context())
+	if err != nil {
+		respondError(c, 500, CodeInternal, err.Error())
+		return
+	}
+	page, total, next := opts.apply(tasks)
+	var body any = page
+	if envelope {
+		if body, err = newListEnvelope(page, fields, listMeta{Total: total, Limit: opts.limit, Offset: opts.offset, NextCursor: next}); err != nil {
+			respondError(c, 500, CodeInternal, err.Error())
+			return
+		}
+		fields = nil
+	}
+	c.Header("Accept-Ranges", itemsRangeUnit)
+	if opts.ranged {
+		respondItemsRange(c, opts, body, len(page), total, fields)
+		return
+	}
+	if next != "" {
+		c.Header("X-Next-Cursor", next)
+	}
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.Header("X-Limit", strconv.Itoa(opts.limit))
+	c.Header("X-Offset", strconv.Itoa(opts.offset))
+	// Add rather than set: on the deprecated routes a successor-version
+	// link is already there.
+	for _, link := range opts.pageLinks(c.Request.URL, total, next) {
+		c.Writer.Header().Add("Link", link)
+	}
+	respondWithETag(c, 200, body, fields)
+}
+
+// respondItemsRange answers a Range: items= request with 206 and body, the
+// window of n of the total matching tasks, or 416 if the range starts past
+// the end.
+func respondItemsRange(c *gin.Context, opts listOptions, body any, n, total int, fields []string) {
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	if opts.offset >= total {
+		c.Header("Content-Range", fmt.Sprintf("%s */%d", itemsRangeUnit, total))
+		respondError(c, 416, CodeRangeNotSatisfiable, fmt.Sprintf("range starts at item %d but only %d tasks match", opts.offset, total))
+		return
+	}
+	c.Header("Content-Range", fmt.Sprintf("%s %d-%d/%d", itemsRangeUnit, opts.offset, opts.offset+n-1, total))
+	respondWithETag(c, 206, body, fields)
+}
+
+func (h *TaskHandler) Get(c *gin.Context) {
+	fields, err := parseFields(c)
+	if err != nil {
+		respondError(c, 400, CodeInvalidRequest, err.Error())
+		return
+	}
+	task, found, err := h.repo.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, 500, CodeInternal, err.Error())
+		return
+	}
+	if !found || task.DeletedAt != nil {
+		respondError(c, 404, CodeTaskNotFound, "task not found")
+		return
+	}
+	respondWithETag(c, 200, task, fields)
+}
+
+func (h *TaskHandler) Create(c *gin.Context) {
+	var task Task
+	if err := bindTask(c, &task); err != nil {
+		respondDecodeError(c, err)
+		return
+	}
+	dedup, err := parseBoolQuery(c, "dedup")
+	if err != nil {
+		respondError(c, 400, CodeInvalidRequest, err.Error())
+		return
+	}
+	if dedup == nil {
+		dedup = &h.dedup
+	}
+	dryRun, ok := parseDryRun(c)
+	if !ok {
+		return
+	}
+	ctx, subject := c.Request.Context(), c.GetString(subjectKey)
+	create := func() (Task, error) {
+		return h.createTask(ctx, subject, task)
+	}
+	// A dry run is neither answered from nor remembered by the cache.
+	if key := c.GetHeader(idempotencyKeyHeader); key != "" && !dryRun {
+		createOnce := create
+		create = func() (Task, error) {
+			created, replayed, err := h.idempotency.Do(subject, key, createOnce)
+			if replayed {
+				c.Header("Idempotent-Replayed", "true")
+			}
+			return created, err
+		}
+	}
+	created, existing, duplicateOf, err := h.createDeduped(ctx, subject, task, *dedup, create)
+	if err != nil {
+		respondModifyError(c, err)
+		return
+	}
+	if existing {
+		setTaskETag(c, created)
+		c.JSON(200, created)
+		return
+	}
+	status := 201
+	if dryRun {
+		status = 200
+	} else {
+		// FullPath keeps the API version prefix the task was created under.
+		c.Header("Location", c.FullPath()+"/"+created.ID)
+	}
+	if duplicateOf != "" {
+		c.Header("Warning", duplicateTitleWarning)
+		c.JSON(status, duplicateCreated{Task: created, DuplicateOf: duplicateOf})
+		return
+	}
+	c.JSON(status, created)
+}
+
+// createDeduped creates task on behalf of subject with create, looking for
+// an open task with the same title first, for every transport that creates
+// tasks. With dedup, such a task is returned in place of a new one and
+// existing is set. Under warnDuplicates the task is created regardless and
+// duplicateOf is the id of the one found.
+func (h *TaskHandler) createDeduped(ctx context.Context, subject string, task Task, dedup bool, create func() (Task, error)) (created Task, existing bool, duplicateOf string, err error) {
+	if dedup {
+		h.dedupMu.Lock()
+		defer h.dedupMu.Unlock()
+		found, ok, err := h.findDuplicate(ctx, subject, task)
+		if err != nil || ok {
+			return found, ok, "", err
+		}
+	}
+	var duplicate Task
+	if h.warnDuplicates {
+		if duplicate, _, err = h.findDuplicate(ctx, subject, task); err != nil {
+			return Task{}, false, "", err
+		}
+	}
+	if created, err = create(); err != nil {
+		return Task{}, false, "", err
+	}
+	// A replayed create would otherwise find the task it made.
+	if duplicate.ID != created.ID {
+		duplicateOf = duplicate.ID
+	}
+	return created, false, duplicateOf, nil
+}
+
+// duplicateTitleWarning is the Warning header of a create that has a
+// duplicate; 199 is the miscellaneous warning code.
+const duplicateTitleWarning = `199 - "duplicate title"`
+
+// duplicateCreated is the response to a create warned about a duplicate:
+// the new task and the id of the existing one with the same title.
+type duplicateCreated struct {
+	Task
+	DuplicateOf string `json:"duplicate_of"`
+}
+
+// findDuplicate returns an active, open task with the same title as task,
+// as compared by sameTitle. When auth is enabled only the subject's own
+// tasks count.
+func (h *TaskHandler) findDuplicate(ctx context.Context, subject string, task Task) (Task, bool, error) {
+	tasks, err := h.repo.List(ctx)
+	if err != nil {
+		return Task{}, false, err
+	}
+	for _, t := range tasks {
+		if t.DeletedAt == nil && !t.Done && (subject == "" || t.Owner == subject) && sameTitle(t.Title, task.Title) {
+			return t, true, nil
+		}
+	}
+	return Task{}, false, nil
+}
+
+// createTask stores a new, already validated task on behalf of subject.
+func (h *TaskHandler) createTask(ctx context.Context, subject string, task Task) (Task, error) {
+	// IDs are assigned by the server unless the client upserts with PUT;
+	// an id in the body is discarded.
+	task.ID = uuid.NewString()
+	return h.createTaskWithID(ctx, subject, task)
+}
+
+// createTaskWithID is createTask for a task whose ID has already been set.
+func (h *TaskHandler) createTaskWithID(ctx context.Context, subject string, task Task) (Task, error) {
+	task.ParentID = ""
+	task.Order = 0
+	task.Comments = []Comment{}
+	assignOwner(&task, subject)
+	now := time.Now().UTC()
+	task.CreatedAt, task.UpdatedAt = now, now
+	task.Version = 1
+	settleCompletion(&task, false)
+	if err := h.as(ctx, subject).Create(ctx, task); err != nil {
+		return Task{}, err
+	}
+	if !isDryRun(ctx) {
+		tasksGauge.Inc()
+	}
+	return task, nil
+}
+
+func (h *TaskHandler) CreateBulk(c *gin.Context) {
+	// Decode without bindTask so every element is checked by validateTask
+	// and reported by index.
+	var tasks []Task
+	if err := bindJSON(c, &tasks); err != nil {
+		respondDecodeError(c, err)
+		return
+	}
+	if len(tasks) == 0 {
+		respondError(c, 400, CodeValidationFailed, "at least one task is required")
+		return
+	}
+	dryRun, ok := parseDryRun(c)
+	if !ok {
+		return
+	}
+
+	var invalid []gin.H
+	for i := range tasks {
+		applyDefaults(&tasks[i])
+		if err := validateTask(tasks[i]); err != nil {
+			invalid = append(invalid, gin.H{"index": i, "error": err.Error()})
+		}
+	}
+	if len(invalid) > 0 {
+		respondErrorDetails(c, 400, CodeValidationFailed, "one or more tasks are invalid", invalid)
+		return
+	}
+
+	now := time.Now().UTC()
+	subject := c.GetString(subjectKey)
+	for i := range tasks {
+		tasks[i].ID = uuid.NewString()
+		tasks[i].ParentID = ""
+		tasks[i].Order = 0
+		tasks[i].Comments = []Comment{}
+		assignOwner(&tasks[i], subject)
+		tasks[i].CreatedAt, tasks[i].UpdatedAt = now, now
+		tasks[i].Version = 1
+		settleCompletion(&tasks[i], false)
+	}
+	if err := h.as(c.Request.Context(), subject).CreateMany(c.Request.Context(), tasks); err != nil {
+		respondModifyError(c, err)
+		return
+	}
+	if dryRun {
+		c.JSON(200, tasks)
+		return
+	}
+	tasksGauge.Add(float64(len(tasks)))
+	c.JSON(201, tasks)
+}
+
+// maxTaskIDLength bounds the ids clients may choose when creating a task
+// with PUT.
+const maxTaskIDLength = 128
+
+// Replace handles PUT, overwriting every client-controlled field. A task
+// that doesn't exist yet is created with the id from the path, so clients
+// that generate ids themselves can retry it safely.
+func (h *TaskHandler) Replace(c *gin.Context) {
+	id := c.Param("id")
+	var updatedTask Task
+	if err := bindTask(c, &updatedTask); err != nil {
+		respondDecodeError(c, err)
+		return
+	}
+	task, found, err := h.replaceTask(c, id, updatedTask)
+	if err != nil {
+		respondModifyError(c, err)
+		return
+	}
+	if found {
+		setTaskETag(c, task)
+		c.JSON(200, task)
+		return
+	}
+
+	// A trashed task still holds its id; it has to be restored instead.
+	if _, exists, err := h.repo.Get(c.Request.Context(), id); err != nil || exists {
+		if err != nil {
+			respondError(c, 500, CodeInternal, err.Error())
+		} else {
+			respondError(c, 404, CodeTaskNotFound, "task not found")
+		}
+		return
+	}
+	// If-Match, even "*", only matches a task that exists.
+	if c.GetHeader("If-Match") != "" {
+		respondError(c, 412, CodePreconditionFailed, errPreconditionFailed.Error())
+		return
+	}
+	if len(id) > maxTaskIDLength {
+		respondError(c, 400, CodeValidationFailed, fmt.Sprintf("id must be at most %d characters", maxTaskIDLength))
+		return
+	}
+	updatedTask.ID = id
+	created, err := h.createTaskWithID(c.Request.Context(), c.GetString(subjectKey), updatedTask)
+	if err != nil {
+		respondModifyError(c, err)
+		return
+	}
+	c.Header("Location", c.Request.URL.Path)
+	setTaskETag(c, created)
+	c.JSON(201, created)
+}
+
+// replaceTask overwrites an active task with updatedTask, honouring
+// If-Match and the body's version, and reports whether the task was found.
+func (h *TaskHandler) replaceTask(c *gin.Context, id string, updatedTask Task) (Task, bool, error) {
+	ifMatch := c.GetHeader("If-Match")
+	return modifyActive(c.Request.Context(), h.asCaller(c), id, func(t *Task) error {
+		if err := checkIfMatch(ifMatch, *t); err != nil {
+			return err
+		}
+		if err := checkVersion(*t, updatedTask.Version); err != nil {
+			return err
+		}
+		// The path decides which task this is; an id in the body is ignored.
+		updatedTask.ID = t.ID
+		updatedTask.CreatedAt = t.CreatedAt
+		updatedTask.ParentID = t.ParentID
+		updatedTask.Order = t.Order
+		updatedTask.Comments = t.Comments
+		updatedTask.UpdatedAt = time.Now().UTC()
+		updatedTask.Version = t.Version + 1
+		settleCompletion(&updatedTask, t.Done)
+		*t = updatedTask
+		return nil
+	})
+}
+
+// Patch applies a merge-style TaskPatch, or a JSON Patch when the body is
+// sent as application/json-patch+json.
+func (h *TaskHandler) Patch(c *gin.Context) {
+	if c.ContentType() == jsonPatchContentType {
+		h.patchJSON(c)
+		return
+	}
+	id := c.Param("id")
+	var patch TaskPatch
+	if err := bindJSON(c, &patch); err != nil {
+		respondDecodeError(c, err)
+		return
+	}
+	task, found, err := h.patchTask(c.Request.Context(), c.GetString(subjectKey), id, patch, c.GetHeader("If-Match"))
+	if err != nil {
+		respondModifyError(c, err)
+		return
+	}
+	if !found {
+		respondError(c, 404, CodeTaskNotFound, "task not found")
+		return
+	}
+	setTaskETag(c, task)
+	c.JSON(200, task)
+}
+
+// patchTask applies patch to an active task on behalf of subject, honouring
+// an optional If-Match value and the patch's version. A patch that changes
+// nothing, such as {}, returns the task as it is, without a new version.
+func (h *TaskHandler) patchTask(ctx context.Context, subject, id string, patch TaskPatch, ifMatch string) (Task, bool, error) {
+	var unchanged Task
+	task, found, err := modifyActive(ctx, h.as(ctx, subject), id, func(t *Task) error {
+		if err := checkIfMatch(ifMatch, *t); err != nil {
+			return err
+		}
+		if patch.Version != nil {
+			if err := checkVersion(*t, *patch.Version); err != nil {
+				return err
+			}
+		}
+		before := *t
+		patch.apply(t)
+		settleCompletion(t, before.Done)
+		if reflect.DeepEqual(*t, before) {
+			unchanged = before
+			return errUnchanged
+		}
+		t.UpdatedAt = time.Now().UTC()
+		t.Version++
+		return validateTask(*t)
+	})
+	if errors.Is(err, errUnchanged) {
+		return unchanged, true, nil
+	}
+	return task, found, err
+}
+
+// Toggle flips a task's done flag in a single atomic update.
+func (h *TaskHandler) Toggle(c *gin.Context) {
+	ifMatch := c.GetHeader("If-Match")
+	task, found, err := modifyActive(c.Request.Context(), h.asCaller(c), c.Param("id"), func(t *Task) error {
+		if err := checkIfMatch(ifMatch, *t); err != nil {
+			return err
+		}
+		t.Done = !t.Done
+		settleCompletion(t, !t.Done)
+		t.UpdatedAt = time.Now().UTC()
+		t.Version++
+		return nil
+	})
+	if err != nil {
+		respondModifyError(c, err)
+		return
+	}
+	if !found {
+		respondError(c, 404, CodeTaskNotFound, "task not found")
+		return
+	}
+	setTaskETag(c, task)
+	c.JSON(200, task)
+}
+
+// batchUpdate is the body of POST /tasks/batch.
+type batchUpdate struct {
+	IDs  []string `json:"ids"`
+	Done *bool    `json:"done"`
+}
+
+// BatchUpdate sets the done flag on every listed active task in one atomic
+// store operation and reports the ids it couldn't find.
+func (h *TaskHandler) BatchUpdate(c *gin.Context) {
+	var req batchUpdate
+	if err := bindJSON(c, &req); err != nil {
+		respondDecodeError(c, err)
+		return
+	}
+	if len(req.IDs) == 0 {
+		respondError(c, 400, CodeValidationFailed, "at least one id is required")
+		return
+	}
+	if req.Done == nil {
+		respondError(c, 400, CodeValidationFailed, "done is required")
+		return
+	}
+	if _, ok := parseDryRun(c); !ok {
+		return
+	}
+
+	// A repeated id would otherwise be changed, and versioned, twice.
+	ids, seen := []string{}, map[string]bool{}
+	for _, id := range req.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	now := time.Now().UTC()
+	updated, missing, err := modifyManyActive(c.Request.Context(), h.asCaller(c), ids, func(t *Task) error {
+		wasDone := t.Done
+		t.Done = *req.Done
+		settleCompletion(t, wasDone)
+		t.UpdatedAt = now
+		t.Version++
+		return nil
+	})
+	if err != nil {
+		respondModifyError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"updated": len(updated), "not_found": missing})
+}
+
+func (h *TaskHandler) DeleteCompleted(c *gin.Context) {
+	trashed, err := h.asCaller(c).TrashCompleted(c.Request.Context(), time.Now().UTC())
+	if err != nil {
+		respondModifyError(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"deleted": len(trashed)})
+}
+
+// Delete moves a task to the trash; ?hard=true removes it for good, whether
+// or not it is in the trash.
+func (h *TaskHandler) Delete(c *gin.Context) {
+	id := c.Param("id")
+	if c.Query("hard") == "true" {
+		found, err := h.asCaller(c).Delete(c.Request.Context(), id)
+		if err != nil {
+			respondModifyError(c, err)
+			return
+		}
+		if !found {
+			respondError(c, 404, CodeTaskNotFound, "task not found")
+			return
+		}
+		tasksGauge.Dec()
+		c.Status(204)
+		return
+	}
+
+	found, err := h.trashTask(c.Request.Context(), c.GetString(subjectKey), id)
+	if err != nil {
+		respondModifyError(c, err)
+		return
+	}
+	if !found {
+		respondError(c, 404, CodeTaskNotFound, "task not found")
+		return
+	}
+	c.Status(204)
+}
+
+// trashTask soft-deletes an active task on behalf of subject.
+func (h *TaskHandler) trashTask(ctx context.Context, subject, id string) (bool, error) {
+	_, found, err := modifyActive(ctx, h.as(ctx, subject), id, func(t *Task) error {
+		now := time.Now().UTC()
+		t.DeletedAt = &now
+		t.UpdatedAt = now
+		t.Version++
+		return nil
+	})
+	return found, err
+}
+
+func (h *TaskHandler) Restore(c *gin.Context) {
+	id := c.Param("id")
+	task, found, err := h.asCaller(c).Modify(c.Request.Context(), id, func(t *Task) error {
+		if t.DeletedAt == nil {
+			return errNotTrashed
+		}
+		t.DeletedAt = nil
+		t.UpdatedAt = time.Now().UTC()
+		t.Version++
+		return nil
+	})
+	if errors.Is(err, errNotTrashed) || (err == nil && !found) {
+		respondError(c, 404, CodeTaskNotFound, "task not found in trash")
+		return
+	}
+	if err != nil {
+		respondModifyError(c, err)
+		return
+	}
+	c.JSON(200, task)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPatchWithoutChangesKeepsVersion(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	created := createTask(t, srv, `{"title":"Unchanged"}`)
+
+	for _, body := range []string{`{}`, `{"title":"Unchanged","done":false}`} {
+		resp, b := request(t, srv, "PATCH", "/v1/tasks/"+created.ID, body)
+		if resp.StatusCode != 200 {
+			t.Fatalf("PATCH %s: status %d: %s", body, resp.StatusCode, b)
+		}
+		got := decode[Task](t, b)
+		if got.Version != created.Version || !got.UpdatedAt.Equal(created.UpdatedAt) {
+			t.Errorf("PATCH %s: version %d, updated_at %s; want %d, %s unchanged",
+				body, got.Version, got.UpdatedAt, created.Version, created.UpdatedAt)
+		}
+	}
+
+	resp, b := request(t, srv, "PATCH", "/v1/tasks/"+created.ID, `{"done":true}`)
+	if resp.StatusCode != 200 {
+		t.Fatalf("PATCH done: status %d: %s", resp.StatusCode, b)
+	}
+	if got := decode[Task](t, b); !got.Done || got.Version != created.Version+1 {
+		t.Errorf("PATCH done: done %v, version %d; want true, %d", got.Done, got.Version, created.Version+1)
+	}
+}
+
+func TestPatchUnknownTask(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	resp, _ := request(t, srv, "PATCH", "/v1/tasks/missing", `{}`)
+	if resp.StatusCode != 404 {
+		t.Errorf("status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestPutKeepsPathID(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	created := createTask(t, srv, `{"title":"Original"}`)
+
+	resp, b := request(t, srv, "PUT", "/v1/tasks/"+created.ID, `{"id":"other","title":"Renamed"}`)
+	if resp.StatusCode != 200 {
+		t.Fatalf("PUT: status %d: %s", resp.StatusCode, b)
+	}
+	if got := decode[Task](t, b); got.ID != created.ID {
+		t.Errorf("PUT response id = %q, want %q", got.ID, created.ID)
+	}
+
+	resp, b = request(t, srv, "GET", "/v1/tasks/"+created.ID, "")
+	if resp.StatusCode != 200 {
+		t.Fatalf("GET by path id: status %d: %s", resp.StatusCode, b)
+	}
+	if got := decode[Task](t, b); got.ID != created.ID || got.Title != "Renamed" {
+		t.Errorf("stored task = %q %q, want %q %q", got.ID, got.Title, created.ID, "Renamed")
+	}
+	if resp, _ := request(t, srv, "GET", "/v1/tasks/other", ""); resp.StatusCode != 404 {
+		t.Errorf("GET by body id: status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestDeleteHasNoBody(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	created := createTask(t, srv, `{"title":"Delete me"}`)
+
+	for _, path := range []string{"/v1/tasks/" + created.ID, "/v1/tasks/" + created.ID + "?hard=true"} {
+		resp, b := request(t, srv, "DELETE", path, "")
+		if resp.StatusCode != 204 {
+			t.Fatalf("DELETE %s: status %d: %s", path, resp.StatusCode, b)
+		}
+		if len(b) != 0 {
+			t.Errorf("DELETE %s: body %q, want none", path, b)
+		}
+	}
+}
+
+func TestBadBodies(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	task := createTask(t, srv, `{"title":"Target"}`)
+
+	cases := []struct {
+		name, contentType, body string
+		status                  int
+		code, message           string
+	}{
+		{"malformed", "application/json", `{"title":`, 400, CodeInvalidRequest, "malformed JSON"},
+		{"syntax error", "application/json", `{"title" "x"}`, 400, CodeInvalidRequest, "malformed JSON at offset"},
+		{"empty", "application/json", ``, 400, CodeInvalidRequest, "request body is empty"},
+		{"wrong type", "application/json", `{"title":7}`, 400, CodeInvalidRequest, "title has the wrong type"},
+		{"not JSON", "text/plain", `title=x`, 415, CodeUnsupportedMedia, "text/plain"},
+		{"unknown field", "application/json", `{"title":"x","priorty":"high"}`, 400, CodeInvalidRequest, `unknown field "priorty"`},
+	}
+	for _, route := range []struct{ method, path string }{
+		{"POST", "/v1/tasks"},
+		{"PUT", "/v1/tasks/" + task.ID},
+		{"PATCH", "/v1/tasks/" + task.ID},
+	} {
+		for _, tc := range cases {
+			req, _ := http.NewRequest(route.method, srv.URL+route.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			body := decode[struct{ Error APIError }](t, b)
+			if resp.StatusCode != tc.status || body.Error.Code != tc.code || !strings.Contains(body.Error.Message, tc.message) {
+				t.Errorf("%s %s, %s body: %d %s %q; want %d %s containing %q", route.method, route.path, tc.name,
+					resp.StatusCode, body.Error.Code, body.Error.Message, tc.status, tc.code, tc.message)
+			}
+		}
+	}
+}
+
+func TestCreateDedup(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	first := createTask(t, srv, `{"title":"Buy milk"}`)
+
+	resp, b := request(t, srv, "POST", "/v1/tasks?dedup=true", `{"title":" buy  MILK "}`)
+	if resp.StatusCode != 200 || decode[Task](t, b).ID != first.ID {
+		t.Errorf("dedup create: status %d: %s; want 200 with task %s", resp.StatusCode, b, first.ID)
+	}
+	if got := createTask(t, srv, `{"title":"Buy milk"}`); got.ID == first.ID {
+		t.Error("create without dedup returned the existing task")
+	}
+
+	// Done tasks don't count as duplicates.
+	done := createTask(t, srv, `{"title":"Call mom","done":true}`)
+	resp, b = request(t, srv, "POST", "/v1/tasks?dedup=true", `{"title":"call mom"}`)
+	if resp.StatusCode != 201 || decode[Task](t, b).ID == done.ID {
+		t.Errorf("dedup create matching a done task: status %d: %s; want 201 and a new task", resp.StatusCode, b)
+	}
+}
+
+func TestPutUpsert(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	path := "/v1/tasks/client-made-id"
+
+	resp, b := request(t, srv, "PUT", path, `{"title":"Made offline"}`)
+	if resp.StatusCode != 201 {
+		t.Fatalf("PUT of a new id: status %d, want 201: %s", resp.StatusCode, b)
+	}
+	created := decode[Task](t, b)
+	if created.ID != "client-made-id" || created.Version != 1 {
+		t.Errorf("created task id %q, version %d; want client-made-id, 1", created.ID, created.Version)
+	}
+	if loc := resp.Header.Get("Location"); loc != path {
+		t.Errorf("Location = %q, want %q", loc, path)
+	}
+
+	resp, b = request(t, srv, "PUT", path, `{"title":"Synced"}`)
+	if resp.StatusCode != 200 {
+		t.Fatalf("PUT of an existing id: status %d, want 200: %s", resp.StatusCode, b)
+	}
+	if updated := decode[Task](t, b); updated.Title != "Synced" || updated.Version != 2 {
+		t.Errorf("updated task %q version %d, want Synced version 2", updated.Title, updated.Version)
+	}
+
+	// Validation applies to both paths.
+	for _, p := range []string{path, "/v1/tasks/another-id"} {
+		if resp, _ := request(t, srv, "PUT", p, `{"title":""}`); resp.StatusCode != 422 {
+			t.Errorf("PUT %s without a title: status %d, want 422", p, resp.StatusCode)
+		}
+	}
+	if resp, _ := request(t, srv, "GET", "/v1/tasks/another-id", ""); resp.StatusCode != 404 {
+		t.Errorf("an invalid upsert created a task: status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestCreateWithoutDoneIsNotDone(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	if task := createTask(t, srv, `{"title":"Fresh"}`); task.Done {
+		t.Error("POST without done created a done task")
+	}
+	resp, b := request(t, srv, "PUT", "/v1/tasks/new-id", `{"title":"Fresh"}`)
+	if task := decode[Task](t, b); resp.StatusCode != 201 || task.Done {
+		t.Errorf("PUT without done: status %d, done %v; want 201 and false", resp.StatusCode, task.Done)
+	}
+}
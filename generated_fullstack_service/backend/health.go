@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Overall health statuses.
+const (
+	HealthOK        = "ok"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+)
+
+// readinessTimeout bounds the dependency checks behind /readyz and verbose
+// health so a hung database fails the probe instead of hanging it.
+const readinessTimeout = 2 * time.Second
+
+// startedAt is when the process started, for the uptime in health reports.
+var startedAt = time.Now()
+
+// HealthReport is the body of GET /health?verbose=true.
+type HealthReport struct {
+	Status        string                 `json:"status"`
+	UptimeSeconds float64                `json:"uptime_seconds"`
+	Goroutines    int                    `json:"goroutines"`
+	Tasks         *int                   `json:"tasks,omitempty"`
+	Checks        map[string]HealthCheck `json:"checks"`
+}
+
+// HealthCheck is the outcome of checking one dependency.
+type HealthCheck struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// registerHealth serves the probes. GET /health by default only says the
+// process is up, which is all a load balancer needs; ?verbose=true also
+// checks the store, answering 503 if it is unreachable. GET /readyz
+// answers 503 while the store is unreachable.
+func registerHealth(r gin.IRoutes, store Store) {
+	getAndHead(r, "/readyz", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+		defer cancel()
+		if err := store.Ping(ctx); err != nil {
+			c.JSON(503, gin.H{"status": "unavailable", "error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"status": "ready"})
+	})
+
+	getAndHead(r, "/health", func(c *gin.Context) {
+		verbose, err := parseBoolQuery(c, "verbose")
+		if err != nil {
+			respondError(c, 400, CodeInvalidRequest, err.Error())
+			return
+		}
+		if verbose == nil || !*verbose {
+			c.JSON(200, gin.H{"status": HealthOK})
+			return
+		}
+		report := checkHealth(c.Request.Context(), store)
+		status := 200
+		if report.Status == HealthUnhealthy {
+			status = 503
+		}
+		c.JSON(status, report)
+	})
+}
+
+// checkHealth pings the store and counts its tasks. An unreachable store
+// makes the service unhealthy; a store that answers pings but fails to
+// count only degrades it.
+func checkHealth(ctx context.Context, store Store) HealthReport {
+	report := HealthReport{
+		Status:        HealthOK,
+		UptimeSeconds: time.Since(startedAt).Seconds(),
+		Goroutines:    runtime.NumGoroutine(),
+		Checks:        map[string]HealthCheck{},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+	report.Checks["store"] = timeCheck(func() error { return store.Ping(ctx) })
+	if report.Checks["store"].Status != HealthOK {
+		report.Status = HealthUnhealthy
+		return report
+	}
+
+	var stats TaskStats
+	report.Checks["task_count"] = timeCheck(func() error {
+		var err error
+		stats, err = store.Stats(ctx, time.Now())
+		return err
+	})
+	if report.Checks["task_count"].Status != HealthOK {
+		report.Status = HealthDegraded
+		return report
+	}
+	report.Tasks = &stats.Total
+	return report
+}
+
+func timeCheck(check func() error) HealthCheck {
+	start := time.Now()
+	err := check()
+	result := HealthCheck{Status: HealthOK, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status, result.Error = HealthUnhealthy, err.Error()
+	}
+	return result
+}
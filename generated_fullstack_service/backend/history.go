@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Change is one field of a task changing from one value to another, as
+// shown in the task's history.
+type Change struct {
+	Field string    `json:"field"`
+	From  any       `json:"from"`
+	To    any       `json:"to"`
+	At    time.Time `json:"at"`
+	// Subject is who made the change, empty when auth is disabled.
+	Subject string `json:"subject,omitempty"`
+}
+
+// History serves the field changes made to an active task since it was
+// created, oldest first, paged with limit and offset. It is read from the
+// audit log, which only records the fields a write actually changed.
+func (h *TaskHandler) History(c *gin.Context) {
+	limit, offset, err := parsePagination(c, h.pageLimit)
+	if err != nil {
+		respondError(c, 400, CodeInvalidRequest, err.Error())
+		return
+	}
+	id := c.Param("id")
+	task, found, err := h.repo.Get(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, 500, CodeInternal, err.Error())
+		return
+	}
+	if !found || task.DeletedAt != nil {
+		respondError(c, 404, CodeTaskNotFound, "task not found")
+		return
+	}
+	changes, err := h.history(id)
+	if err != nil {
+		respondError(c, 500, CodeInternal, err.Error())
+		return
+	}
+	c.Header("X-Total-Count", strconv.Itoa(len(changes)))
+	c.Header("X-Limit", strconv.Itoa(limit))
+	c.Header("X-Offset", strconv.Itoa(offset))
+	if offset > len(changes) {
+		offset = len(changes)
+	}
+	c.JSON(200, changes[offset:min(offset+limit, len(changes))])
+}
+
+// historyIgnoredFields are left out of a task's history. Comments are
+// served by their own endpoint; adding one doesn't revise the task.
+var historyIgnoredFields = map[string]bool{"comments": true}
+
+// history flattens the audit entries of a task into its field changes. The
+// create entry is left out: it lists every field, none of which changed.
+// Fields changed together are sorted by name.
+func (h *TaskHandler) history(taskID string) ([]Change, error) {
+	_, total, err := h.audit.ListAudit(taskID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	entries, _, err := h.audit.ListAudit(taskID, total, 0)
+	if err != nil {
+		return nil, err
+	}
+	changes := []Change{}
+	for _, e := range entries {
+		if e.Action == AuditCreate || e.Action == AuditDelete {
+			continue
+		}
+		fields := make([]string, 0, len(e.Changes))
+		for field := range e.Changes {
+			if !historyIgnoredFields[field] {
+				fields = append(fields, field)
+			}
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			fc := e.Changes[field]
+			changes = append(changes, Change{Field: field, From: fc.From, To: fc.To, At: e.At, Subject: e.Subject})
+		}
+	}
+	return changes, nil
+}
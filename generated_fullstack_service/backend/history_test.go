@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	task := createTask(t, srv, `{"title":"Draft"}`)
+	path := "/v1/tasks/" + task.ID
+
+	if resp, b := request(t, srv, "PATCH", path, `{"title":"Final","tags":["a"]}`); resp.StatusCode != 200 {
+		t.Fatalf("patch: status %d: %s", resp.StatusCode, b)
+	}
+	if resp, b := request(t, srv, "POST", path+"/comments", `{"body":"Ship it"}`); resp.StatusCode != 201 {
+		t.Fatalf("comment: status %d: %s", resp.StatusCode, b)
+	}
+	if resp, b := request(t, srv, "PATCH", path, `{"title":"Final"}`); resp.StatusCode != 200 {
+		t.Fatalf("unchanged patch: status %d: %s", resp.StatusCode, b)
+	}
+
+	resp, b := request(t, srv, "GET", path+"/history", "")
+	if resp.StatusCode != 200 {
+		t.Fatalf("history: status %d: %s", resp.StatusCode, b)
+	}
+	changes := decode[[]Change](t, b)
+	if len(changes) != 2 || changes[0].Field != "tags" || changes[1].Field != "title" {
+		t.Fatalf("history = %s, want the tags and title changes only", b)
+	}
+	if changes[1].From != "Draft" || changes[1].To != "Final" {
+		t.Errorf("title change = %+v, want Draft to Final", changes[1])
+	}
+	if got := resp.Header.Get("X-Total-Count"); got != "2" {
+		t.Errorf("X-Total-Count = %q, want 2", got)
+	}
+
+	if resp, _ := request(t, srv, "GET", "/v1/tasks/missing/history", ""); resp.StatusCode != 404 {
+		t.Errorf("history of an unknown task: status %d, want 404", resp.StatusCode)
+	}
+}
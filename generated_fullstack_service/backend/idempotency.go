@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	idempotencyTTL       = 24 * time.Hour
+)
+
+// IdempotencyCache remembers the task created for each Idempotency-Key so a
+// retried create returns the original result instead of a duplicate. Keys
+// are scoped to the caller's subject, so one caller can't replay, and read,
+// the task another created.
+type IdempotencyCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+type idempotencyEntry struct {
+	// done is closed once task is set; concurrent requests with the same
+	// key wait on it instead of creating a second task.
+	done    chan struct{}
+	task    Task
+	expires time.Time
+}
+
+func NewIdempotencyCache(ttl time.Duration) *IdempotencyCache {
+	return &IdempotencyCache{ttl: ttl, entries: map[string]*idempotencyEntry{}}
+}
+
+// Do runs create at most once per subject and key within the TTL. It reports
+// whether the returned task was replayed from an earlier request. A failed
+// create is not remembered, so the client can retry it.
+func (c *IdempotencyCache) Do(subject, key string, create func() (Task, error)) (Task, bool, error) {
+	return c.do(subject+"\x00"+key, create)
+}
+
+func (c *IdempotencyCache) do(key string, create func() (Task, error)) (Task, bool, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && time.Now().Before(e.expires) {
+		c.mu.Unlock()
+		<-e.done
+		// The entry is dropped if create failed; start over in that case.
+		c.mu.Lock()
+		cur, ok := c.entries[key]
+		c.mu.Unlock()
+		if !ok || cur != e {
+			return c.do(key, create)
+		}
+		return e.task, true, nil
+	}
+	e := &idempotencyEntry{done: make(chan struct{}), expires: time.Now().Add(c.ttl)}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	task, err := create()
+	if err != nil {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+		close(e.done)
+		return Task{}, false, err
+	}
+	e.task = task
+	close(e.done)
+	return task, false, nil
+}
+
+// Clear forgets every key, so repeats of earlier requests create again.
+func (c *IdempotencyCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// RunCleanup drops expired keys every interval until ctx is cancelled.
+func (c *IdempotencyCache) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.mu.Lock()
+			for key, e := range c.entries {
+				if now.After(e.expires) {
+					delete(c.entries, key)
+				}
+			}
+			c.mu.Unlock()
+		}
+	}
+}
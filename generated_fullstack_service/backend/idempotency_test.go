@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestIdempotencyKeyIsPerSubject(t *testing.T) {
+	cfg := testConfig()
+	cfg.JWTSecret = "test-secret"
+	srv := newTestServer(t, cfg)
+	alice, bob := testToken(t, cfg.JWTSecret, "alice"), testToken(t, cfg.JWTSecret, "bob")
+
+	create := func(token, title string) (Task, bool) {
+		t.Helper()
+		resp, b := request(t, srv, "POST", "/v1/tasks", `{"title":"`+title+`"}`, "Authorization", token, idempotencyKeyHeader, "shared-key")
+		if resp.StatusCode != 201 {
+			t.Fatalf("create: status %d: %s", resp.StatusCode, b)
+		}
+		return decode[Task](t, b), resp.Header.Get("Idempotent-Replayed") == "true"
+	}
+
+	first, _ := create(alice, "Alice's task")
+	other, replayed := create(bob, "Bob's task")
+	if replayed || other.ID == first.ID || other.Title != "Bob's task" || other.Owner != "bob" {
+		t.Errorf("bob with alice's key got %+v (replayed %v), want his own new task", other, replayed)
+	}
+	again, replayed := create(alice, "Alice's task")
+	if !replayed || again.ID != first.ID {
+		t.Errorf("alice's retry got %s (replayed %v), want her task %s replayed", again.ID, replayed, first.ID)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+)
+
+// jsonPatchContentType selects an RFC 6902 JSON Patch body on PATCH
+// /tasks/:id; any other JSON type is a merge-style TaskPatch.
+const jsonPatchContentType = "application/json-patch+json"
+
+// jsonPatchOps are the operations a JSON Patch may use. move and copy have
+// little use on a task and are refused.
+var jsonPatchOps = []string{"add", "remove", "replace", "test"}
+
+// serverOwnedFields are the task fields a JSON Patch may test but not
+// change, in the order they are reported.
+var serverOwnedFields = []string{"id", "created_at", "updated_at", "parent_id", "order", "comments", "deleted_at", "version"}
+
+var (
+	errInvalidPatch    = errors.New("invalid JSON Patch")
+	errPatchTestFailed = errors.New("JSON Patch test failed")
+)
+
+// decodeJSONPatch reads the request body as a JSON Patch document, checking
+// that it only uses jsonPatchOps.
+func decodeJSONPatch(c *gin.Context) (jsonpatch.Patch, error) {
+	if c.Request.Body == nil {
+		return nil, errEmptyBody
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, errEmptyBody
+	}
+	patch, err := jsonpatch.DecodePatch(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: the body must be an array of operations", errInvalidPatch)
+	}
+	for i, op := range patch {
+		if !containsString(jsonPatchOps, op.Kind()) {
+			return nil, fmt.Errorf("%w: operation %d has op %q; must be one of add, remove, replace, test", errInvalidPatch, i, op.Kind())
+		}
+	}
+	return patch, nil
+}
+
+// patchJSON handles PATCH /tasks/:id with a JSON Patch body.
+func (h *TaskHandler) patchJSON(c *gin.Context) {
+	patch, err := decodeJSONPatch(c)
+	if err != nil {
+		if errors.Is(err, errInvalidPatch) {
+			respondError(c, 400, CodeInvalidRequest, err.Error())
+		} else {
+			respondDecodeError(c, err)
+		}
+		return
+	}
+	task, found, err := h.applyJSONPatch(c.Request.Context(), c.GetString(subjectKey), c.Param("id"), patch, c.GetHeader("If-Match"))
+	if err != nil {
+		respondModifyError(c, err)
+		return
+	}
+	if !found {
+		respondError(c, 404, CodeTaskNotFound, "task not found")
+		return
+	}
+	setTaskETag(c, task)
+	c.JSON(200, task)
+}
+
+// applyJSONPatch applies patch to the JSON form of an active task, the one
+// GET returns, on behalf of subject, honouring an optional If-Match value.
+// A patch that changes a server-owned field fails validation. Of the rest,
+// only what PUT may change is kept, with PUT's defaults for removed fields,
+// and it is validated like a PUT body. A test of /version guards against
+// concurrent changes.
+func (h *TaskHandler) applyJSONPatch(ctx context.Context, subject, id string, patch jsonpatch.Patch, ifMatch string) (Task, bool, error) {
+	return modifyActive(ctx, h.as(ctx, subject), id, func(t *Task) error {
+		if err := checkIfMatch(ifMatch, *t); err != nil {
+			return err
+		}
+		doc, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		opts := jsonpatch.NewApplyOptions()
+		opts.SupportNegativeIndices = false
+		doc, err = patch.ApplyWithOptions(doc, opts)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return fmt.Errorf("%w: %v", errPatchTestFailed, err)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", errInvalidPatch, err)
+		}
+		if err := checkServerOwnedFields(*t, doc); err != nil {
+			return err
+		}
+		var patched Task
+		dec := json.NewDecoder(bytes.NewReader(doc))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&patched); err != nil {
+			return fmt.Errorf("%w: %v", errInvalidPatch, describeBindError(err))
+		}
+		applyDefaults(&patched)
+		patched.ID = t.ID
+		patched.CreatedAt = t.CreatedAt
+		patched.ParentID = t.ParentID
+		patched.Order = t.Order
+		patched.Comments = t.Comments
+		patched.DeletedAt = t.DeletedAt
+		patched.UpdatedAt = time.Now().UTC()
+		patched.Version = t.Version + 1
+		settleCompletion(&patched, t.Done)
+		if err := validateTask(patched); err != nil {
+			return err
+		}
+		*t = patched
+		return nil
+	})
+}
+
+// checkServerOwnedFields reports every server-owned field whose value in the
+// patched document doc differs from t's, comparing them as JSON values so a
+// patch that writes back the stored value is let through.
+func checkServerOwnedFields(t Task, doc []byte) error {
+	var patched map[string]any
+	if err := json.Unmarshal(doc, &patched); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidPatch, err)
+	}
+	stored := taskFields(&t)
+	var errs fieldErrors
+	for _, field := range serverOwnedFields {
+		if !reflect.DeepEqual(patched[field], stored[field]) {
+			errs.add(field, "/"+field+" is set by the server and cannot be patched")
+		}
+	}
+	return errs.err()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestJSONPatchServerOwnedFields(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	task := createTask(t, srv, `{"title":"Patch me"}`)
+	path := "/v1/tasks/" + task.ID
+	patch := func(body string) (int, []byte) {
+		t.Helper()
+		resp, b := request(t, srv, "PATCH", path, body, "Content-Type", jsonPatchContentType)
+		return resp.StatusCode, b
+	}
+
+	for _, tc := range []struct{ patch, field string }{
+		{`[{"op":"replace","path":"/id","value":"other"}]`, "id"},
+		{`[{"op":"replace","path":"/version","value":99}]`, "version"},
+		{`[{"op":"replace","path":"/created_at","value":"2000-01-01T00:00:00Z"}]`, "created_at"},
+		{`[{"op":"replace","path":"/order","value":3}]`, "order"},
+		{`[{"op":"add","path":"/comments/-","value":{"id":"c","body":"sneaky"}}]`, "comments"},
+		{`[{"op":"add","path":"/deleted_at","value":"2000-01-01T00:00:00Z"}]`, "deleted_at"},
+		{`[{"op":"replace","path":"","value":{"title":"Whole"}}]`, "id"},
+	} {
+		status, b := patch(tc.patch)
+		if status != 422 {
+			t.Errorf("%s: status %d, want 422: %s", tc.patch, status, b)
+			continue
+		}
+		got := decode[struct {
+			Error struct {
+				Code    string
+				Details []FieldError
+			}
+		}](t, b).Error
+		if got.Code != CodeValidationFailed || len(got.Details) == 0 || got.Details[0].Field != tc.field {
+			t.Errorf("%s: %s, want VALIDATION_FAILED naming %s first", tc.patch, b, tc.field)
+		}
+	}
+
+	_, b := request(t, srv, "GET", path, "")
+	if got := decode[Task](t, b); got.Version != task.Version || got.ID != task.ID || len(got.Comments) != 0 {
+		t.Fatalf("rejected patches changed the task: %s", b)
+	}
+
+	status, b := patch(`[{"op":"test","path":"/version","value":` + strconv.Itoa(task.Version) + `},{"op":"replace","path":"/id","value":"` + task.ID + `"},{"op":"replace","path":"/title","value":"Patched"}]`)
+	if got := decode[Task](t, b); status != 200 || got.Title != "Patched" || got.Version != task.Version+1 {
+		t.Errorf("testing /version and writing back the id: status %d: %s", status, b)
+	}
+}
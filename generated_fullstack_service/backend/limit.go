@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var errTaskLimitReached = errors.New("task limit reached")
+
+// limitedStore is a TaskRepository decorator that fails creates with
+// errTaskLimitReached when they would take the number of active tasks past
+// max. mu is shared by every limitedStore on the same handler, so the count
+// and the create are one step and concurrent creates can't both slip under
+// the limit.
+type limitedStore struct {
+	TaskRepository
+	max int
+	mu  *sync.Mutex
+}
+
+func (s *limitedStore) Create(ctx context.Context, t Task) error {
+	return s.CreateMany(ctx, []Task{t})
+}
+
+func (s *limitedStore) CreateMany(ctx context.Context, ts []Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, err := s.Stats(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if stats.Total+len(ts) > s.max {
+		return errTaskLimitReached
+	}
+	return s.TaskRepository.CreateMany(ctx, ts)
+}
+
+// ReplaceAll fails if ts holds more than max active tasks, whatever the
+// store holds now.
+func (s *limitedStore) ReplaceAll(ctx context.Context, ts []Task) ([]Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	active := 0
+	for _, t := range ts {
+		if t.DeletedAt == nil {
+			active++
+		}
+	}
+	if active > s.max {
+		return nil, errTaskLimitReached
+	}
+	return s.TaskRepository.ReplaceAll(ctx, ts)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestMaxTasks(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxTasks = 2
+	srv := newTestServer(t, cfg)
+	first := createTask(t, srv, `{"title":"One"}`)
+	createTask(t, srv, `{"title":"Two"}`)
+
+	resp, b := request(t, srv, "POST", "/v1/tasks", `{"title":"Three"}`)
+	if resp.StatusCode != 403 {
+		t.Fatalf("create past the limit: status %d, want 403: %s", resp.StatusCode, b)
+	}
+	if got := decode[struct{ Error APIError }](t, b).Error; got.Code != CodeTaskLimitReached || got.Message != "task limit reached" {
+		t.Errorf("error = %+v, want %s \"task limit reached\"", got, CodeTaskLimitReached)
+	}
+	if resp, _ := request(t, srv, "POST", "/v1/tasks/bulk", `[{"title":"Three"}]`); resp.StatusCode != 403 {
+		t.Errorf("bulk create past the limit: status %d, want 403", resp.StatusCode)
+	}
+
+	// A trashed task no longer counts.
+	if resp, _ := request(t, srv, "DELETE", "/v1/tasks/"+first.ID, ""); resp.StatusCode != 204 {
+		t.Fatalf("delete: status %d", resp.StatusCode)
+	}
+	createTask(t, srv, `{"title":"Three"}`)
+}
+
+func TestMaxTasksConcurrentCreates(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxTasks = 5
+	srv := newTestServer(t, cfg)
+
+	var wg sync.WaitGroup
+	statuses := make(chan int, 20)
+	for i := 0; i < cap(statuses); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, _ := request(t, srv, "POST", "/v1/tasks", `{"title":"Racing"}`)
+			statuses <- resp.StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+	created := 0
+	for status := range statuses {
+		switch status {
+		case 201:
+			created++
+		case 403:
+		default:
+			t.Errorf("unexpected status %d", status)
+		}
+	}
+	if created != cfg.MaxTasks {
+		t.Errorf("%d creates succeeded, want %d", created, cfg.MaxTasks)
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// taskLess holds the ascending comparison for every sortable field.
+var taskLess = map[string]func(a, b Task) bool{
+	"title":      func(a, b Task) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) },
+	"done":       func(a, b Task) bool { return !a.Done && b.Done },
+	"priority":   func(a, b Task) bool { return priorityRank[a.Priority] < priorityRank[b.Priority] },
+	"created_at": func(a, b Task) bool { return a.CreatedAt.Before(b.CreatedAt) },
+	"updated_at": func(a, b Task) bool { return a.UpdatedAt.Before(b.UpdatedAt) },
+	"order":      func(a, b Task) bool { return orderRank(a) < orderRank(b) },
+}
+
+// orderRank places tasks that were never moved, with Order 0, last.
+func orderRank(t Task) int {
+	if t.Order == 0 {
+		return math.MaxInt
+	}
+	return t.Order
+}
+
+// listOptions are the filter, sort and paging parameters of GET /tasks.
+type listOptions struct {
+	// trashed selects soft-deleted tasks instead of active ones.
+	trashed bool
+	// archived selects archived active tasks instead of the others. The
+	// trash holds both.
+	archived  bool
+	limit     int
+	offset    int
+	filter    taskFilter
+	sortField string
+	sortDesc  bool
+	// keyset selects cursor pagination, which ignores offset; cursor is the
+	// position to continue from, nil for the first page.
+	keyset bool
+	cursor *pageCursor
+	// ranged is set by a Range: items= header, which is read into offset
+	// and limit in place of the query parameters.
+	ranged bool
+}
+
+// parseListOptions reads the list query parameters and Range header,
+// applying defaultLimit when neither gives a page size.
+func parseListOptions(c *gin.Context, defaultLimit int) (listOptions, error) {
+	var opts listOptions
+	var err error
+	if opts.limit, opts.offset, err = parsePagination(c, defaultLimit); err != nil {
+		return opts, err
+	}
+	if opts.filter, err = parseTaskFilter(c); err != nil {
+		return opts, err
+	}
+	if len(c.QueryArray("archived")) > 1 {
+		return opts, errors.New("archived may only be given once")
+	}
+	if archived, err := parseBoolQuery(c, "archived"); err != nil {
+		return opts, err
+	} else if archived != nil {
+		opts.archived = *archived
+	}
+	if opts.sortField, opts.sortDesc, err = parseSort(c); err != nil {
+		return opts, err
+	}
+	if header := c.GetHeader("Range"); strings.HasPrefix(header, itemsRangeUnit+"=") {
+		for _, param := range []string{"limit", "offset", "cursor"} {
+			if _, ok := c.GetQuery(param); ok {
+				return opts, errors.New("Range cannot be combined with " + param)
+			}
+		}
+		first, last, err := parseItemsRange(header)
+		if err != nil {
+			return opts, err
+		}
+		opts.ranged, opts.offset, opts.limit = true, first, maxPageLimit
+		// Compared before adding 1, which would overflow for a last of
+		// math.MaxInt.
+		if last >= 0 && last-first < maxPageLimit {
+			opts.limit = last - first + 1
+		}
+	}
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		if c.Query("offset") != "" {
+			return opts, errors.New("cursor and offset cannot be combined")
+		}
+		opts.keyset = true
+		if cursor != "" {
+			cur, err := decodeCursor(cursor, opts.cursorSort())
+			if err != nil {
+				return opts, err
+			}
+			opts.cursor = &cur
+		}
+	}
+	return opts, nil
+}
+
+// cursorSort is the sort spec cursor pages are ordered by.
+func (o listOptions) cursorSort() string {
+	field := o.sortField
+	if field == "" {
+		field = defaultCursorSort
+	}
+	if o.sortDesc {
+		return "-" + field
+	}
+	return field
+}
+
+// apply filters and sorts tasks, then returns the requested page along with
+// the number of tasks that matched before paging and, for cursor pagination,
+// the cursor of the following page.
+func (o listOptions) apply(tasks []Task) ([]Task, int, string) {
+	tasks = o.match(tasks)
+	if o.keyset {
+		page, next := keysetPage(tasks, o.cursorSort(), o.cursor, o.limit)
+		return page, len(tasks), next
+	}
+	return paginate(tasks, o.limit, o.offset), len(tasks), ""
+}
+
+// match returns the tasks selected by the filters, in the requested order.
+func (o listOptions) match(tasks []Task) []Task {
+	keep := o.filter.predicate(time.Now())
+	tasks = filterTasks(tasks, func(t Task) bool {
+		if o.trashed {
+			return t.DeletedAt != nil && keep(t)
+		}
+		return t.DeletedAt == nil && t.Archived == o.archived && keep(t)
+	})
+	if o.sortField != "" {
+		sortTasks(tasks, o.sortField, o.sortDesc)
+	} else {
+		sortTasks(tasks, "order", false)
+	}
+	return tasks
+}
+
+// pageLinks returns RFC 8288 Link values for the pages around the one
+// requested at u, which matched total tasks: first, prev, next and last for
+// offset paging, with prev left out on the first page and next on the last.
+// Cursor pages can only be walked forwards, so they only get first and,
+// given the next cursor, next. Every other query parameter is kept as is.
+func (o listOptions) pageLinks(u *url.URL, total int, nextCursor string) []string {
+	link := func(rel, param, value string) string {
+		q := u.Query()
+		q.Set(param, value)
+		return fmt.Sprintf("<%s?%s>; rel=%q", u.Path, q.Encode(), rel)
+	}
+	if o.keyset {
+		links := []string{link("first", "cursor", "")}
+		if nextCursor != "" {
+			links = append(links, link("next", "cursor", nextCursor))
+		}
+		return links
+	}
+	if o.limit == 0 {
+		return nil
+	}
+	offset := func(rel string, n int) string { return link(rel, "offset", strconv.Itoa(n)) }
+	links := []string{offset("first", 0)}
+	if o.offset > 0 {
+		links = append(links, offset("prev", max(o.offset-o.limit, 0)))
+	}
+	if o.offset < total-o.limit {
+		links = append(links, offset("next", o.offset+o.limit))
+	}
+	last := 0
+	if total > 0 {
+		last = (total - 1) / o.limit * o.limit
+	}
+	return append(links, offset("last", last))
+}
+
+// parsePagination reads the limit and offset query parameters, applying
+// defaultLimit when none is given.
+func parsePagination(c *gin.Context, defaultLimit int) (limit, offset int, err error) {
+	limit = defaultLimit
+	if v := c.Query("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return 0, 0, errors.New("limit must be a non-negative integer")
+		}
+		if limit > maxPageLimit {
+			return 0, 0, errors.New("limit must be at most " + strconv.Itoa(maxPageLimit))
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+// itemsRangeUnit is the Range unit of GET /tasks, counting tasks.
+const itemsRangeUnit = "items"
+
+// parseItemsRange reads a Range header of one items range, such as
+// items=0-49, or items=50- for everything from the 51st task on. last is -1
+// for an open range. Whether the range is satisfiable depends on the total,
+// which the caller checks; windows larger than maxPageLimit are cut short
+// there too. Positions too large for an int are read as math.MaxInt, which
+// no list reaches.
+func parseItemsRange(header string) (first, last int, err error) {
+	spec := strings.TrimPrefix(header, itemsRangeUnit+"=")
+	from, to, ok := strings.Cut(spec, "-")
+	first, ferr := atoiSaturating(from)
+	if !ok || ferr != nil || first < 0 || strings.Contains(to, ",") {
+		return 0, 0, errors.New("Range must be a single items=<first>-<last> range")
+	}
+	if to == "" {
+		return first, -1, nil
+	}
+	last, err = atoiSaturating(to)
+	if err != nil || last < first {
+		return 0, 0, errors.New("Range must be a single items=<first>-<last> range with last at least first")
+	}
+	return first, last, nil
+}
+
+// atoiSaturating is strconv.Atoi, except that a number too large for an int
+// is math.MaxInt rather than an error.
+func atoiSaturating(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if errors.Is(err, strconv.ErrRange) && n == math.MaxInt {
+		return n, nil
+	}
+	return n, err
+}
+
+// parseBoolQuery reads an optional true/false query parameter. A nil result
+// means the parameter was absent.
+func parseBoolQuery(c *gin.Context, name string) (*bool, error) {
+	v, ok := c.GetQuery(name)
+	if !ok {
+		return nil, nil
+	}
+	switch v {
+	case "true":
+		done := true
+		return &done, nil
+	case "false":
+		done := false
+		return &done, nil
+	}
+	return nil, errors.New(name + " must be true or false")
+}
+
+// parseSort reads the sort query parameter. A leading "-" selects descending
+// order; an empty field means manual order, then insertion order.
+func parseSort(c *gin.Context) (field string, desc bool, err error) {
+	field = c.Query("sort")
+	if strings.HasPrefix(field, "-") {
+		field, desc = field[1:], true
+	}
+	if field == "" {
+		return "", false, nil
+	}
+	if _, ok := taskLess[field]; !ok {
+		return "", false, errors.New("cannot sort by " + strconv.Quote(field))
+	}
+	return field, desc, nil
+}
+
+// matchesQuery reports whether the task title contains q, ignoring case.
+func matchesQuery(t Task, q string) bool {
+	return strings.Contains(strings.ToLower(t.Title), strings.ToLower(q))
+}
+
+// filterTasks returns the tasks for which keep returns true.
+func filterTasks(tasks []Task, keep func(Task) bool) []Task {
+	out := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		if keep(t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// sortTasks orders tasks in place by field. Ties keep their existing order.
+func sortTasks(tasks []Task, field string, desc bool) {
+	less := taskLess[field]
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if desc {
+			return less(tasks[j], tasks[i])
+		}
+		return less(tasks[i], tasks[j])
+	})
+}
+
+// paginate returns the window of tasks selected by limit and offset.
+func paginate(tasks []Task, limit, offset int) []Task {
+	if offset >= len(tasks) {
+		return []Task{}
+	}
+	end := offset + limit
+	if end > len(tasks) {
+		end = len(tasks)
+	}
+	return tasks[offset:end]
+}
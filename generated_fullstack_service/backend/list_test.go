@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestItemsRange(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	for _, title := range []string{"a", "b", "c"} {
+		createTask(t, srv, `{"title":"`+title+`"}`)
+	}
+
+	cases := []struct {
+		header       string
+		status       int
+		n            int
+		contentRange string
+	}{
+		{"items=0-1", 206, 2, "items 0-1/3"},
+		{"items=1-", 206, 2, "items 1-2/3"},
+		{"items=0-9223372036854775807", 206, 3, "items 0-2/3"},
+		{"items=2-99999999999999999999", 206, 1, "items 2-2/3"},
+		{"items=3-5", 416, 0, "items */3"},
+		{"items=9223372036854775807-", 416, 0, "items */3"},
+		{"items=99999999999999999999-99999999999999999999", 416, 0, "items */3"},
+		{"items=2-1", 400, 0, ""},
+		{"items=0-1,2-3", 400, 0, ""},
+	}
+	for _, tc := range cases {
+		resp, b := request(t, srv, "GET", "/v1/tasks", "", "Range", tc.header)
+		if resp.StatusCode != tc.status {
+			t.Errorf("Range %s: status %d, want %d: %s", tc.header, resp.StatusCode, tc.status, b)
+			continue
+		}
+		if got := resp.Header.Get("Content-Range"); got != tc.contentRange {
+			t.Errorf("Range %s: Content-Range %q, want %q", tc.header, got, tc.contentRange)
+		}
+		if tc.status == 206 {
+			if got := decode[[]Task](t, b); len(got) != tc.n {
+				t.Errorf("Range %s: %d tasks, want %d", tc.header, len(got), tc.n)
+			}
+		}
+	}
+}
+
+func TestHugeOffset(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	createTask(t, srv, `{"title":"a"}`)
+	resp, b := request(t, srv, "GET", "/v1/tasks?offset=9223372036854775807&limit=100", "")
+	if got := decode[[]Task](t, b); resp.StatusCode != 200 || len(got) != 0 {
+		t.Errorf("status %d, %d tasks; want 200 and none", resp.StatusCode, len(got))
+	}
+	links := resp.Header.Values("Link")
+	if len(links) == 0 {
+		t.Fatal("no Link header")
+	}
+	for _, link := range links {
+		if strings.Contains(link, `rel="next"`) {
+			t.Errorf("Link %q offers a next page past the end", link)
+		}
+	}
+}
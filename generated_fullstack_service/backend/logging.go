@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxLoggedBodyBytes is how much of a request body is logged at debug
+	// level.
+	maxLoggedBodyBytes = 1 << 10
+	// defaultSlowRequestMS is the latency, in milliseconds, above which
+	// SlowRequestLog warns.
+	defaultSlowRequestMS = 500
+)
+
+// setupLogging makes slog's default logger write cfg.LogFormat lines at
+// cfg.Level and above to stdout. The standard log package is routed through
+// the same handler, so every line the server writes shares one format. Gin's
+// own route listing and warnings are only printed at debug level, unless
+// GIN_MODE says otherwise.
+// logLevel is the default logger's minimum level, which a config reload can
+// change.
+var logLevel slog.LevelVar
+
+func setupLogging(cfg Config) {
+	if _, set := os.LookupEnv(gin.EnvGinMode); !set && cfg.Level() > slog.LevelDebug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	logLevel.Set(cfg.Level())
+	opts := &slog.HandlerOptions{Level: &logLevel}
+	var handler slog.Handler = slog.NewJSONHandler(os.Stdout, opts)
+	if cfg.LogFormat == "text" {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// bodyCapture keeps the first limit bytes read from a request body, leaving
+// what the handler reads unchanged.
+type bodyCapture struct {
+	io.ReadCloser
+	limit     int
+	buf       []byte
+	truncated bool
+}
+
+func (b *bodyCapture) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := b.limit - len(b.buf); n > room {
+		b.buf = append(b.buf, p[:room]...)
+		b.truncated = true
+	} else {
+		b.buf = append(b.buf, p[:n]...)
+	}
+	return n, err
+}
+
+// SlowRequestLog logs a warning for every request that takes longer than
+// threshold, on top of its usual request line, so pathological requests stand
+// out in production logs. The request_id matches that line. Requests to the
+// excluded paths, such as streams, which are meant to stay open, are never
+// reported.
+func SlowRequestLog(threshold time.Duration, excludedPaths ...string) gin.HandlerFunc {
+	excluded := make(map[string]bool, len(excludedPaths))
+	for _, p := range excludedPaths {
+		excluded[p] = true
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		elapsed := time.Since(start)
+		if elapsed <= threshold || excluded[c.Request.URL.Path] {
+			return
+		}
+		slog.Default().LogAttrs(c.Request.Context(), slog.LevelWarn, "slow request",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", c.Writer.Status()),
+			slog.Float64("latency_ms", float64(elapsed.Microseconds())/1000),
+			slog.Int64("threshold_ms", threshold.Milliseconds()),
+			slog.String("request_id", c.GetString(requestIDKey)),
+		)
+	}
+}
@@ -1,90 +1,133 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
+	"context"
+	"errors"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 )
 
-type Task struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
-	Done  bool   `json:"done"`
-}
-
-var tasks = []Task{}
-
-// CORS middleware
-func CORSMiddleware() gin.HandlerFunc {
-	return gin.HandlerFunc(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
+// redirectToHTTPS sends every request to the same host and path on the
+// HTTPS port. 308 keeps the method and body of non-GET requests.
+func redirectToHTTPS(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
 		}
-
-		c.Next()
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
 	})
 }
 
 func main() {
-	r := gin.New()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	r.Use(gin.Logger())
-	r.Use(gin.Recovery())
-	r.Use(CORSMiddleware())
+	cfg, err := LoadConfig()
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
+	setupLogging(cfg)
 
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
-
-	r.GET("/tasks", func(c *gin.Context) {
-		c.JSON(200, tasks)
-	})
+	shutdownTracing, err := setupTracing(ctx, cfg.TracingEndpoint)
+	if err != nil {
+		log.Fatalf("tracing: %v", err)
+	}
 
-	r.POST("/tasks", func(c *gin.Context) {
-		var task Task
-		err := c.BindJSON(&task)
-		if err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
-			return
+	store, closeStore, err := connectStore(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeStore()
+	if cfg.Seed {
+		n, err := seedStore(ctx, store)
+		switch {
+		case err != nil:
+			log.Fatalf("seed: %v", err)
+		case n > 0:
+			log.Printf("seeded the store with %d sample tasks", n)
+		default:
+			log.Println("store is not empty; not seeding it")
 		}
-		tasks = append(tasks, task)
-		c.JSON(201, task)
-	})
+	}
+	if cfg.CacheTTL > 0 {
+		store = NewCachingStore(store, cfg.CacheTTL)
+	}
+	if tasks, err := store.List(ctx); err == nil {
+		tasksGauge.Set(float64(len(tasks)))
+	}
 
-	r.PUT("/tasks/:id", func(c *gin.Context) {
-		id := c.Param("id")
-		for i, task := range tasks {
-			if task.ID == id {
-				var updatedTask Task
-				err := c.BindJSON(&updatedTask)
-				if err != nil {
-					c.JSON(400, gin.H{"error": err.Error()})
-					return
-				}
-				tasks[i] = updatedTask
-				c.JSON(200, updatedTask)
-				return
-			}
-		}
-		c.JSON(404, gin.H{"error": "task not found"})
-	})
+	r, tasks := NewRouter(store, cfg)
+	go tasks.idempotency.RunCleanup(ctx, time.Hour)
+	if cfg.ArchiveAfterDays > 0 && cfg.ArchiveInterval > 0 {
+		go tasks.RunArchiver(ctx, time.Duration(cfg.ArchiveAfterDays)*24*time.Hour, cfg.ArchiveInterval)
+		log.Printf("archiving done tasks idle for %d days, every %s", cfg.ArchiveAfterDays, cfg.ArchiveInterval)
+	}
+	if len(cfg.Webhooks.URLs) > 0 {
+		go NewWebhookDispatcher(cfg.Webhooks.URLs, cfg.Webhooks.Secret, cfg.EventFormat).Run(ctx, tasks.events)
+	}
 
-	r.DELETE("/tasks/:id", func(c *gin.Context) {
-		id := c.Param("id")
-		for i, task := range tasks {
-			if task.ID == id {
-				tasks = append(tasks[:i], tasks[i+1:]...)
-				c.JSON(204, gin.H{})
-				return
-			}
+	var handler http.Handler = r
+	if cfg.RequestTimeout > 0 {
+		handler = Timeout(cfg.RequestTimeout, longLivedPaths...)(r)
+	}
+	srv := &http.Server{
+		Addr:    cfg.Addr(),
+		Handler: handler,
+	}
+	// Shutdown waits for active requests, so end the event streams first.
+	srv.RegisterOnShutdown(tasks.events.Close)
+
+	go func() {
+		var err error
+		if cfg.TLS.Enabled() {
+			log.Printf("serving HTTPS on %s", cfg.Addr())
+			err = srv.ListenAndServeTLS(cfg.TLS.Cert, cfg.TLS.Key)
+		} else {
+			log.Printf("serving on %s", cfg.Addr())
+			err = srv.ListenAndServe()
 		}
-		c.JSON(404, gin.H{"error": "task not found"})
-	})
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("listen: %v", err)
+		}
+	}()
+	var redirect *http.Server
+	if cfg.TLS.Redirect {
+		redirect = &http.Server{
+			Addr:              ":" + strconv.Itoa(cfg.TLS.RedirectPort),
+			Handler:           redirectToHTTPS(cfg.Port),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			log.Printf("redirecting HTTP on %s to HTTPS", redirect.Addr)
+			if err := redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("listen: %v", err)
+			}
+		}()
+	}
 
-	log.Fatal(r.Run(":8080"))
-}
\ No newline at end of file
+	<-ctx.Done()
+	stop()
+	log.Println("shutting down gracefully")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
+	if redirect != nil {
+		redirect.Shutdown(shutdownCtx)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Printf("tracing: flush spans: %v", err)
+	}
+}
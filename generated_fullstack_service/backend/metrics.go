@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests by method, route and status.",
+	}, []string{"method", "path", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "path"})
+
+	tasksGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "tasks",
+		Help: "Number of tasks currently in the store.",
+	})
+
+	cacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "task_cache_hits_total",
+		Help: "Store reads answered from the cache, by operation.",
+	}, []string{"op"})
+
+	cacheMisses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "task_cache_misses_total",
+		Help: "Store reads that went through to the store, by operation.",
+	}, []string{"op"})
+)
+
+// Metrics records request counts and latencies. Requests are labelled by
+// route template rather than raw path to keep label cardinality bounded.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			path = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequestsTotal.WithLabelValues(c.Request.Method, path, status).Inc()
+		httpRequestDuration.WithLabelValues(c.Request.Method, path).Observe(time.Since(start).Seconds())
+	}
+}
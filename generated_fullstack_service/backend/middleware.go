@@ -0,0 +1,346 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	// requestIDKey is the gin context key holding the current request id.
+	requestIDKey = "request_id"
+	// maxRequestIDLength bounds client-supplied ids so they can't bloat logs.
+	maxRequestIDLength = 128
+)
+
+// RequestID tags each request with the caller's X-Request-ID, or a new UUID
+// when none (or an oversized one) is sent, and echoes it on the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	return id != "" && len(id) <= maxRequestIDLength
+}
+
+// Error codes carried in APIError.Code. Clients should branch on these
+// rather than on messages, which may change.
+const (
+	CodeInvalidRequest      = "INVALID_REQUEST"
+	CodeValidationFailed    = "VALIDATION_FAILED"
+	CodeTaskNotFound        = "TASK_NOT_FOUND"
+	CodeSubtaskNotFound     = "SUBTASK_NOT_FOUND"
+	CodeCommentNotFound     = "COMMENT_NOT_FOUND"
+	CodeRouteNotFound       = "ROUTE_NOT_FOUND"
+	CodeNothingToUndo       = "NOTHING_TO_UNDO"
+	CodeUndoConflict        = "UNDO_CONFLICT"
+	CodeVersionConflict     = "VERSION_CONFLICT"
+	CodeDuplicateID         = "DUPLICATE_ID"
+	CodePreconditionFailed  = "PRECONDITION_FAILED"
+	CodePayloadTooLarge     = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMedia    = "UNSUPPORTED_MEDIA_TYPE"
+	CodePatchTestFailed     = "PATCH_TEST_FAILED"
+	CodeUnauthorized        = "UNAUTHORIZED"
+	CodeForbidden           = "FORBIDDEN"
+	CodeTaskLimitReached    = "TASK_LIMIT_REACHED"
+	CodeRateLimited         = "RATE_LIMITED"
+	CodeTimeout             = "REQUEST_TIMEOUT"
+	CodeReadOnly            = "READ_ONLY"
+	CodeRangeNotSatisfiable = "RANGE_NOT_SATISFIABLE"
+	CodeInternal            = "INTERNAL_ERROR"
+)
+
+// APIError is the body of every error response, wrapped as {"error": ...}.
+type APIError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+	// Details holds machine-readable context specific to the code, such as
+	// the current version on a VERSION_CONFLICT.
+	Details any `json:"details,omitempty"`
+}
+
+// respondError aborts the request with an APIError body that carries the
+// request id, so a failure reported by a user can be found in the logs.
+func respondError(c *gin.Context, status int, code, msg string) {
+	respondErrorDetails(c, status, code, msg, nil)
+}
+
+func respondErrorDetails(c *gin.Context, status int, code, msg string, details any) {
+	c.AbortWithStatusJSON(status, gin.H{"error": APIError{
+		Code:      code,
+		Message:   msg,
+		RequestID: c.GetString(requestIDKey),
+		Details:   details,
+	}})
+}
+
+// corsOrigins is the set of allowed origins, which a config reload can
+// replace while requests are being served.
+type corsOrigins struct {
+	allowed atomic.Pointer[map[string]bool]
+}
+
+func newCORSOrigins(origins []string) *corsOrigins {
+	o := &corsOrigins{}
+	o.set(origins)
+	return o
+}
+
+func (o *corsOrigins) set(origins []string) {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
+	o.allowed.Store(&allowed)
+}
+
+// CORSMiddleware allows cross-origin requests from the given origins. A "*"
+// entry allows any origin, in which case credentials are not allowed since
+// browsers reject that combination.
+func CORSMiddleware(origins *corsOrigins) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed := *origins.allowed.Load()
+		wildcard := allowed["*"]
+		origin := c.GetHeader("Origin")
+		switch {
+		case wildcard:
+			c.Header("Access-Control-Allow-Origin", "*")
+		case origin != "" && allowed[origin]:
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+		if !wildcard {
+			c.Header("Vary", "Origin")
+		}
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Idempotency-Key, If-Match, If-None-Match, If-Modified-Since, Range, accept, origin, Cache-Control, X-Requested-With, X-Request-ID")
+		c.Header("Access-Control-Expose-Headers", "ETag, Location, Retry-After, X-Request-ID, X-Total-Count, X-Limit, X-Offset, X-Next-Cursor, Link, Dry-Run, Content-Range, Accept-Ranges, Warning")
+		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, HEAD, PUT, PATCH, DELETE")
+		// Preflights are answered by the OPTIONS routes from registerOptions.
+		c.Next()
+	}
+}
+
+// registerOptions answers OPTIONS on every route registered so far with 204
+// and an Allow header listing the methods that path supports, which also
+// narrows Access-Control-Allow-Methods for preflights. It must run after
+// all other routes are added.
+func registerOptions(r *gin.Engine) {
+	methods := map[string][]string{}
+	var paths []string
+	for _, route := range r.Routes() {
+		// The profiles mix a catch-all with a static route, which gin
+		// can't take a third method for; pprof clients don't send OPTIONS.
+		if strings.HasPrefix(route.Path, "/debug/pprof/") {
+			continue
+		}
+		// Their existence is only revealed to holders of the admin token.
+		if adminTokenPaths[route.Path] {
+			continue
+		}
+		if _, ok := methods[route.Path]; !ok {
+			paths = append(paths, route.Path)
+		}
+		methods[route.Path] = append(methods[route.Path], route.Method)
+	}
+	for _, path := range paths {
+		allowed := append(methods[path], "OPTIONS")
+		sort.Strings(allowed)
+		allow := strings.Join(allowed, ", ")
+		r.OPTIONS(path, func(c *gin.Context) {
+			c.Header("Allow", allow)
+			c.Header("Access-Control-Allow-Methods", allow)
+			c.Status(204)
+		})
+	}
+}
+
+// respondModifyError maps an error returned from a store Modify call to a
+// response: rule violations are the client's fault, anything else is ours.
+func respondModifyError(c *gin.Context, err error) {
+	var conflict *VersionConflictError
+	if errors.As(err, &conflict) {
+		respondErrorDetails(c, 409, CodeVersionConflict, conflict.Error(), gin.H{"current_version": conflict.Current})
+		return
+	}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		respondValidationError(c, verr)
+		return
+	}
+	status, code := modifyErrorStatus(err)
+	respondError(c, status, code, err.Error())
+}
+
+// modifyErrorStatus returns the HTTP status and error code for an error
+// returned from a store Modify call.
+func modifyErrorStatus(err error) (int, string) {
+	var verr *ValidationError
+	var conflict *VersionConflictError
+	switch {
+	case errors.As(err, &verr) && len(verr.Fields) > 0:
+		return 422, CodeValidationFailed
+	case errors.As(err, &verr):
+		return 400, CodeValidationFailed
+	case errors.Is(err, errPreconditionFailed):
+		return 412, CodePreconditionFailed
+	case errors.Is(err, errNotOwner):
+		return 403, CodeForbidden
+	case errors.Is(err, errTaskLimitReached):
+		return 403, CodeTaskLimitReached
+	case errors.Is(err, errDuplicateID):
+		return 409, CodeDuplicateID
+	case errors.Is(err, errPatchTestFailed):
+		return 409, CodePatchTestFailed
+	case errors.Is(err, errInvalidPatch):
+		return 400, CodeInvalidRequest
+	case errors.As(err, &conflict):
+		return 409, CodeVersionConflict
+	}
+	return 500, CodeInternal
+}
+
+// respondDecodeError reports a request body that could not be decoded or
+// validated: 413 when it ran into the body size limit, 400 otherwise.
+func respondDecodeError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondError(c, 413, CodePayloadTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+		return
+	}
+	err = describeBindError(err)
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		respondValidationError(c, verr)
+		return
+	}
+	respondError(c, 400, CodeInvalidRequest, err.Error())
+}
+
+// respondValidationError answers 422 listing every broken field in details
+// for a task body, and 400 for any other rule violation.
+func respondValidationError(c *gin.Context, verr *ValidationError) {
+	if len(verr.Fields) > 0 {
+		respondErrorDetails(c, 422, CodeValidationFailed, verr.Error(), verr.Fields)
+		return
+	}
+	respondError(c, 400, CodeValidationFailed, verr.Error())
+}
+
+// BodySizeLimit caps request bodies at maxBytes; handlers that read past it
+// get an *http.MaxBytesError. Excluded paths enforce limits of their own.
+func BodySizeLimit(maxBytes int64, excludedPaths ...string) gin.HandlerFunc {
+	excluded := make(map[string]bool, len(excludedPaths))
+	for _, p := range excludedPaths {
+		excluded[p] = true
+	}
+
+	return func(c *gin.Context) {
+		if !excluded[c.Request.URL.Path] {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		}
+		c.Next()
+	}
+}
+
+// JSONContentType answers POST, PUT and PATCH requests whose body isn't JSON
+// with 415, rather than leaving the handler to fail decoding it. A body with
+// no Content-Type is read as JSON unless requireType is set, and a request
+// with no body passes either way. Excluded paths take other media types.
+func JSONContentType(requireType bool, excludedPaths ...string) gin.HandlerFunc {
+	excluded := make(map[string]bool, len(excludedPaths))
+	for _, p := range excludedPaths {
+		excluded[p] = true
+	}
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case "POST", "PUT", "PATCH":
+		default:
+			c.Next()
+			return
+		}
+		ct := c.ContentType()
+		switch {
+		case excluded[c.Request.URL.Path]:
+		case ct != "" && !isJSONContentType(ct):
+			respondError(c, 415, CodeUnsupportedMedia, fmt.Sprintf("Content-Type must be application/json, not %s", ct))
+			return
+		case ct == "" && requireType && c.Request.ContentLength != 0:
+			respondError(c, 415, CodeUnsupportedMedia, "Content-Type must be application/json")
+			return
+		}
+		c.Next()
+	}
+}
+
+// isJSONContentType reports whether ct, without parameters, is
+// application/json or a +json type such as application/merge-patch+json.
+func isJSONContentType(ct string) bool {
+	return ct == "application/json" || strings.HasSuffix(ct, "+json")
+}
+
+// Deprecated marks responses from routes that have moved under prefix,
+// pointing clients at the replacement with a successor-version link.
+func Deprecated(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Writer.Header().Add("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", prefix, c.Request.URL.Path))
+		c.Next()
+	}
+}
+
+// StructuredLogger writes one log line per request through the default slog
+// logger, which setupLogging configures. Server errors are logged at error
+// level so they can be alerted on. At debug level the line also carries the
+// start of the request body, as far as the handler read it.
+func StructuredLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		ctx := c.Request.Context()
+		var body *bodyCapture
+		if c.Request.Body != nil && slog.Default().Enabled(ctx, slog.LevelDebug) {
+			body = &bodyCapture{ReadCloser: c.Request.Body, limit: maxLoggedBodyBytes}
+			c.Request.Body = body
+		}
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("request_id", c.GetString(requestIDKey)),
+		}
+		if body != nil && len(body.buf) > 0 {
+			attrs = append(attrs, slog.String("body", string(body.buf)))
+			if body.truncated {
+				attrs = append(attrs, slog.Bool("body_truncated", true))
+			}
+		}
+		slog.Default().LogAttrs(ctx, level, "request", attrs...)
+	}
+}
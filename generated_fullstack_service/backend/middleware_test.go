@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBodySizeLimit(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxBodyBytes = 64
+	srv := newTestServer(t, cfg)
+	task := createTask(t, srv, `{"title":"Small"}`)
+	big := `{"title":"` + strings.Repeat("x", 100) + `"}`
+
+	for _, r := range []struct{ method, path string }{
+		{"POST", "/v1/tasks"},
+		{"PUT", "/v1/tasks/" + task.ID},
+		{"PATCH", "/v1/tasks/" + task.ID},
+		{"POST", "/v1/tasks/bulk"},
+	} {
+		resp, b := request(t, srv, r.method, r.path, big)
+		if resp.StatusCode != 413 {
+			t.Errorf("%s %s: status %d, want 413: %s", r.method, r.path, resp.StatusCode, b)
+			continue
+		}
+		if code := errorCode(t, b); code != CodePayloadTooLarge {
+			t.Errorf("%s %s: code %q, want %q", r.method, r.path, code, CodePayloadTooLarge)
+		}
+	}
+}
+
+func TestOptionsAllow(t *testing.T) {
+	cfg := testConfig()
+	cfg.AdminToken = "admin"
+	srv := newTestServer(t, cfg)
+
+	for path, want := range map[string]string{
+		"/v1/tasks":                 "GET, HEAD, OPTIONS, POST",
+		"/v1/tasks/abc":             "DELETE, GET, HEAD, OPTIONS, PATCH, PUT",
+		"/v1/tasks/abc/comments":    "GET, HEAD, OPTIONS, POST",
+		"/v1/tasks/abc/comments/c1": "DELETE, OPTIONS",
+		"/v1/tasks/abc/move":        "OPTIONS, POST",
+		"/v1/tasks/stream":          "GET, OPTIONS",
+		"/v1/tasks/completed":       "DELETE, OPTIONS",
+		"/v1/tasks/abc/subtasks/s1": "DELETE, OPTIONS, PUT",
+		"/tasks":                    "GET, HEAD, OPTIONS, POST",
+		"/health":                   "GET, HEAD, OPTIONS",
+	} {
+		resp, _ := request(t, srv, "OPTIONS", path, "")
+		if resp.StatusCode != 204 {
+			t.Errorf("OPTIONS %s: status %d, want 204", path, resp.StatusCode)
+			continue
+		}
+		if got := resp.Header.Get("Allow"); got != want {
+			t.Errorf("OPTIONS %s: Allow %q, want %q", path, got, want)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Methods"); got != want {
+			t.Errorf("OPTIONS %s: Access-Control-Allow-Methods %q, want %q", path, got, want)
+		}
+	}
+
+	if resp, _ := request(t, srv, "OPTIONS", adminResetPath, ""); resp.Header.Get("Allow") != "" {
+		t.Errorf("OPTIONS %s revealed the admin route: Allow %q", adminResetPath, resp.Header.Get("Allow"))
+	}
+}
+
+func TestHead(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	task := createTask(t, srv, `{"title":"Exists"}`)
+
+	resp, b := request(t, srv, "HEAD", "/v1/tasks/"+task.ID, "")
+	if resp.StatusCode != 200 || len(b) != 0 {
+		t.Errorf("HEAD an existing task: status %d, %d body bytes; want 200 and none", resp.StatusCode, len(b))
+	}
+	if resp.Header.Get("ETag") == "" {
+		t.Error("HEAD an existing task: no ETag")
+	}
+	if resp, _ := request(t, srv, "HEAD", "/v1/tasks/missing", ""); resp.StatusCode != 404 {
+		t.Errorf("HEAD an unknown task: status %d, want 404", resp.StatusCode)
+	}
+	if resp, _ := request(t, srv, "HEAD", "/v1/tasks", ""); resp.StatusCode != 200 {
+		t.Errorf("HEAD the list: status %d, want 200", resp.StatusCode)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFiles holds the schema migrations of each SQL backend, one
+// directory per backend. A file is named <version>_<name>.sql and is applied
+// once, in version order; applied files must never be edited, only followed
+// by new ones.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migration is one embedded .sql file.
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// loadMigrations reads the migrations in dir, sorted by version.
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var migrations []migration
+	seen := map[int]string{}
+	for _, e := range entries {
+		file := e.Name()
+		base, ok := strings.CutSuffix(file, ".sql")
+		if e.IsDir() || !ok {
+			continue
+		}
+		v, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(v)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a positive version", file)
+		}
+		if prev, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", prev, file, version)
+		}
+		seen[version] = file
+		body, err := fs.ReadFile(fsys, path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{version: version, name: name, sql: string(body)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// migrator is the backend-specific half of runMigrations.
+type migrator interface {
+	// ensureTable creates schema_migrations if it doesn't exist.
+	ensureTable(ctx context.Context) error
+	// apply runs m and records it in schema_migrations in one transaction,
+	// unless it is already recorded there, and reports whether it ran. It
+	// holds whatever lock the backend needs for another process starting
+	// against the same database not to apply m as well.
+	apply(ctx context.Context, m migration) (bool, error)
+}
+
+// runMigrations applies the migrations in dir that db hasn't had yet, in
+// order, logging each one. It stops at the first that fails, which is rolled
+// back, so a broken migration keeps the server from starting rather than
+// leaving it to run against a half-migrated schema.
+func runMigrations(ctx context.Context, db migrator, dir string) error {
+	migrations, err := loadMigrations(migrationFiles, dir)
+	if err != nil {
+		return err
+	}
+	if err := db.ensureTable(ctx); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	for _, m := range migrations {
+		ran, err := db.apply(ctx, m)
+		if err != nil {
+			return fmt.Errorf("migration %d_%s: %w", m.version, m.name, err)
+		}
+		if ran {
+			slog.Info("applied migration", "backend", path.Base(dir), "version", m.version, "name", m.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+-- IF NOT EXISTS adopts a database created before migrations were tracked,
+-- which the last release without them brought up to this schema at startup.
+-- seq records insertion order, which List preserves.
+CREATE TABLE IF NOT EXISTS tasks (
+	id TEXT PRIMARY KEY,
+	title TEXT NOT NULL,
+	done BOOLEAN NOT NULL DEFAULT FALSE,
+	seq BIGSERIAL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	version INTEGER NOT NULL DEFAULT 1,
+	priority TEXT NOT NULL DEFAULT 'medium',
+	due_date TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ,
+	tags TEXT[] NOT NULL DEFAULT '{}',
+	subtasks JSONB NOT NULL DEFAULT '[]',
+	recurrence TEXT NOT NULL DEFAULT 'none',
+	parent_id TEXT NOT NULL DEFAULT '',
+	owner TEXT NOT NULL DEFAULT '',
+	sort_order INTEGER NOT NULL DEFAULT 0,
+	comments JSONB NOT NULL DEFAULT '[]'
+);
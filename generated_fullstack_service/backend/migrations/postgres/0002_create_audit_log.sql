@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+	id BIGSERIAL PRIMARY KEY,
+	at TIMESTAMPTZ NOT NULL,
+	subject TEXT NOT NULL DEFAULT '',
+	action TEXT NOT NULL,
+	task_id TEXT NOT NULL,
+	changes JSONB NOT NULL DEFAULT '{}',
+	undoes BIGINT NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS audit_log_task_id ON audit_log (task_id);
+CREATE INDEX IF NOT EXISTS audit_log_subject ON audit_log (subject, id);
+CREATE INDEX IF NOT EXISTS audit_log_undoes ON audit_log (undoes);
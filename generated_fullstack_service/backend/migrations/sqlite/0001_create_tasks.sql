@@ -0,0 +1,24 @@
+-- IF NOT EXISTS adopts a database created before migrations were tracked,
+-- which the last release without them brought up to this schema at startup.
+CREATE TABLE IF NOT EXISTS tasks (
+	id TEXT PRIMARY KEY,
+	title TEXT NOT NULL,
+	done INTEGER NOT NULL DEFAULT 0,
+	created_at TEXT NOT NULL DEFAULT '',
+	updated_at TEXT NOT NULL DEFAULT '',
+	version INTEGER NOT NULL DEFAULT 1,
+	priority TEXT NOT NULL DEFAULT 'medium',
+	due_date TEXT,
+	deleted_at TEXT,
+	-- tags holds a JSON array of strings.
+	tags TEXT NOT NULL DEFAULT '[]',
+	-- subtasks holds a JSON array of Subtask objects.
+	subtasks TEXT NOT NULL DEFAULT '[]',
+	recurrence TEXT NOT NULL DEFAULT 'none',
+	parent_id TEXT NOT NULL DEFAULT '',
+	owner TEXT NOT NULL DEFAULT '',
+	-- order is a reserved word.
+	sort_order INTEGER NOT NULL DEFAULT 0,
+	-- comments holds a JSON array of Comment objects.
+	comments TEXT NOT NULL DEFAULT '[]'
+);
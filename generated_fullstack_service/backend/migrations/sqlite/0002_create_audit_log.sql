@@ -0,0 +1,13 @@
+-- changes holds the entry's JSON-encoded field diff.
+CREATE TABLE IF NOT EXISTS audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	at TEXT NOT NULL,
+	subject TEXT NOT NULL DEFAULT '',
+	action TEXT NOT NULL,
+	task_id TEXT NOT NULL,
+	changes TEXT NOT NULL DEFAULT '{}',
+	undoes INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS audit_log_task_id ON audit_log (task_id);
+CREATE INDEX IF NOT EXISTS audit_log_subject ON audit_log (subject, id);
+CREATE INDEX IF NOT EXISTS audit_log_undoes ON audit_log (undoes);
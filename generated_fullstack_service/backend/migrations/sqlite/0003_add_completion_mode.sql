@@ -0,0 +1,2 @@
+-- completion_mode is manual or auto; see settleCompletion.
+ALTER TABLE tasks ADD COLUMN completion_mode TEXT NOT NULL DEFAULT 'manual';
@@ -0,0 +1,3 @@
+-- archived is set by the archiver, or by clients, to hide a task from the
+-- default list.
+ALTER TABLE tasks ADD COLUMN archived INTEGER NOT NULL DEFAULT 0;
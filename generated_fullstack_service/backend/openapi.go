@@ -0,0 +1,582 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// taskSchemaOverrides adds constraints the Go types can't express to the
+// schema derived from Task.
+var taskSchemaOverrides = map[string]gin.H{
+	"id":         {"readOnly": true},
+	"title":      {"minLength": 1, "maxLength": maxTitleLength},
+	"done":       {"default": false},
+	"priority":   {"enum": []string{PriorityLow, PriorityMedium, PriorityHigh}, "default": PriorityMedium},
+	"created_at": {"readOnly": true},
+	"updated_at": {"readOnly": true},
+	"deleted_at": {"readOnly": true},
+	"tags":       {"uniqueItems": true},
+	"recurrence": {"enum": recurrences, "default": RecurrenceNone},
+	"completion_mode": {"enum": completionModes, "default": CompletionManual,
+		"description": "auto derives done from the subtasks, and flipping done cascades to them"},
+	"parent_id": {"readOnly": true},
+	"order":     {"readOnly": true, "description": "Manual position set with POST /v1/tasks/{id}/move; 0 if never placed"},
+	"comments":  {"readOnly": true, "description": "Managed through /v1/tasks/{id}/comments"},
+}
+
+// schemaFor derives a JSON schema from a Go type, following encoding/json's
+// field naming so the spec can't drift from what the handlers actually send.
+func schemaFor(t reflect.Type) gin.H {
+	if t == reflect.TypeOf(time.Time{}) {
+		return gin.H{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := schemaFor(t.Elem())
+		s["nullable"] = true
+		return s
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return gin.H{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.Slice:
+		return gin.H{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Struct:
+		props := gin.H{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" || !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = schemaFor(f.Type)
+			if strings.Contains(f.Tag.Get("binding"), "required") {
+				required = append(required, name)
+			}
+		}
+		s := gin.H{"type": "object", "properties": props}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+	return gin.H{}
+}
+
+func taskSchema() gin.H {
+	s := schemaFor(reflect.TypeOf(Task{}))
+	props := s["properties"].(gin.H)
+	for name, extra := range taskSchemaOverrides {
+		if p, ok := props[name].(gin.H); ok {
+			for k, v := range extra {
+				p[k] = v
+			}
+		}
+	}
+	return s
+}
+
+func subtaskSchema() gin.H {
+	s := schemaFor(reflect.TypeOf(Subtask{}))
+	props := s["properties"].(gin.H)
+	props["id"].(gin.H)["readOnly"] = true
+	props["title"].(gin.H)["minLength"] = 1
+	props["title"].(gin.H)["maxLength"] = maxTitleLength
+	return s
+}
+
+func commentSchema() gin.H {
+	s := schemaFor(reflect.TypeOf(Comment{}))
+	props := s["properties"].(gin.H)
+	props["id"].(gin.H)["readOnly"] = true
+	props["created_at"].(gin.H)["readOnly"] = true
+	props["author"].(gin.H)["description"] = "The token subject when auth is enabled; otherwise taken from the body"
+	props["body"].(gin.H)["minLength"] = 1
+	props["body"].(gin.H)["maxLength"] = maxCommentLength
+	return s
+}
+
+func ref(name string) gin.H {
+	return gin.H{"$ref": "#/components/schemas/" + name}
+}
+
+func jsonContent(schema gin.H) gin.H {
+	return gin.H{"application/json": gin.H{"schema": schema}}
+}
+
+func jsonResponse(desc string, schema gin.H) gin.H {
+	return gin.H{"description": desc, "content": jsonContent(schema)}
+}
+
+// readResponse is a jsonResponse that is also available as XML, chosen with
+// the Accept header.
+func readResponse(desc string, schema gin.H) gin.H {
+	r := jsonResponse(desc, schema)
+	r["content"].(gin.H)["application/xml"] = gin.H{"schema": schema}
+	return r
+}
+
+// listResponse is a readResponse for a page of tasks, which JSON clients may
+// ask to have enveloped.
+func listResponse(desc string, taskList gin.H) gin.H {
+	r := readResponse(desc, taskList)
+	r["content"].(gin.H)["application/json"] = gin.H{"schema": gin.H{"oneOf": []gin.H{taskList, ref("TaskListEnvelope")}}}
+	return r
+}
+
+// ndjsonResponse adds the application/x-ndjson export of ?format=ndjson to a
+// list response.
+func ndjsonResponse(r gin.H) gin.H {
+	r["content"].(gin.H)["application/x-ndjson"] = gin.H{"schema": ref("Task")}
+	return r
+}
+
+func errorResponse(desc string) gin.H {
+	return jsonResponse(desc, ref("Error"))
+}
+
+// openAPISpec describes every route registered by TaskHandler under /v1.
+func openAPISpec() gin.H {
+	idParam := gin.H{"name": "id", "in": "path", "required": true, "schema": gin.H{"type": "string"}}
+	subIDParam := gin.H{"name": "subId", "in": "path", "required": true, "schema": gin.H{"type": "string"}}
+	commentIDParam := gin.H{"name": "commentId", "in": "path", "required": true, "schema": gin.H{"type": "string"}}
+	query := func(name, typ, desc string) gin.H {
+		return gin.H{"name": name, "in": "query", "description": desc, "schema": gin.H{"type": typ}}
+	}
+	fieldsParam := query("fields", "string", "Comma-separated task fields to return; others are left out")
+	formatParam := query("format", "string", "csv for a CSV export or ndjson for a streamed one, one JSON task per line; both ignore paging")
+	rangeParam := gin.H{"name": "Range", "in": "header", "description": "items=<first>-<last>, 0-based and inclusive, in place of limit and offset; last may be left out", "schema": gin.H{"type": "string"}}
+	dryRunParam := query("dry_run", "boolean", "Check the change and return its response without storing anything; answered with Dry-Run: true")
+	listParams := []gin.H{
+		query("q", "string", "Case-insensitive title substring"),
+		query("done", "boolean", "Filter by completion"),
+		query("priority", "string", "Filter by priority; repeat to accept several"),
+		query("owner", "string", "Filter by owner; repeat to accept several"),
+		query("tag", "string", "Only tasks with this tag; repeat to require several, or any of them with match=any"),
+		query("overdue", "boolean", "Filter by overdue status; with tz, a task is overdue once the local day it was due on has ended"),
+		query("due", "string", "today for tasks due within the current day, in tz if given, else UTC"),
+		query("tz", "string", "IANA time zone, such as America/New_York, that overdue and due count days in"),
+		query("archived", "boolean", "true for archived tasks instead of the others; the trash lists both"),
+		query("match", "string", "all (default) for tasks matching every filter given, any for tasks matching at least one"),
+		query("sort", "string", "Sort field, prefixed with - for descending"),
+		query("limit", "integer", "Page size (default 20 or DEFAULT_PAGE_LIMIT, max 100)"),
+		query("offset", "integer", "Number of tasks to skip"),
+		query("cursor", "string", "Use cursor pagination: empty for the first page, then the previous page's X-Next-Cursor"),
+		fieldsParam,
+	}
+	taskList := gin.H{"type": "array", "items": ref("Task")}
+	envelopeParam := query("envelope", "boolean", `Wrap the page as {"data", "meta"} with the total, limit, offset and next cursor; also asked for with Accept: application/json; profile="envelope"`)
+	writeHeaders := []gin.H{
+		{"name": "If-Match", "in": "header", "schema": gin.H{"type": "string"}, "description": "ETag the write is conditional on"},
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info":    gin.H{"title": "Task Service API", "version": "1.0.0"},
+		"paths": gin.H{
+			"/health": gin.H{"get": gin.H{
+				"summary":    "Liveness probe, with dependency detail on request",
+				"parameters": []gin.H{query("verbose", "boolean", "Check the store and report uptime, goroutines and task count")},
+				"responses": gin.H{
+					"200": jsonResponse("Process is up; with verbose, status is ok or degraded", schemaFor(reflect.TypeOf(HealthReport{}))),
+					"503": jsonResponse("With verbose, the store is unreachable", schemaFor(reflect.TypeOf(HealthReport{}))),
+				},
+			}},
+			"/version": gin.H{"get": gin.H{
+				"summary":   "Build information",
+				"responses": gin.H{"200": jsonResponse("The running build", schemaFor(reflect.TypeOf(BuildInfo{})))},
+			}},
+			"/readyz": gin.H{"get": gin.H{
+				"summary": "Readiness probe",
+				"responses": gin.H{
+					"200": gin.H{"description": "Store is reachable"},
+					"503": gin.H{"description": "Store is unavailable"},
+				},
+			}},
+			"/v1/tasks": gin.H{
+				"get": gin.H{
+					"summary":    "List tasks",
+					"parameters": append(listParams, formatParam, rangeParam, envelopeParam),
+					"responses": gin.H{
+						"200": ndjsonResponse(listResponse("A page of tasks; the total is in X-Total-Count and the neighbouring pages in Link", taskList)),
+						"206": listResponse("The window of tasks asked for with Range, described by Content-Range", taskList),
+						"304": gin.H{"description": "Not modified since the If-None-Match ETag or If-Modified-Since date"},
+						"400": errorResponse("Invalid query parameter or Range"),
+						"416": errorResponse("Range starts past the last matching task"),
+					},
+				},
+				"post": gin.H{
+					"summary": "Create a task",
+					"parameters": []gin.H{
+						{"name": "Idempotency-Key", "in": "header", "schema": gin.H{"type": "string"}},
+						query("dedup", "boolean", "Return an open task with the same title instead of creating one; overrides DEDUP"),
+						dryRunParam,
+					},
+					"requestBody": gin.H{"required": true, "content": jsonContent(ref("Task"))},
+					"responses": gin.H{
+						"200": jsonResponse("An open task with the same title already exists", ref("Task")),
+						"201": jsonResponse("Created; with DUPLICATE_WARNING, a task with the same title adds a Warning header and its id as duplicate_of", ref("Task")),
+						"400": errorResponse("Malformed body"),
+						"415": errorResponse("Body isn't JSON"),
+						"422": errorResponse("Invalid fields, all listed in details"),
+						"401": errorResponse("Missing or invalid token"),
+					},
+				},
+			},
+			"/v1/tasks.csv": gin.H{"get": gin.H{
+				"summary":    "Export matching tasks as CSV",
+				"parameters": listParams,
+				"responses": gin.H{
+					"200": gin.H{"description": "CSV download", "content": gin.H{"text/csv": gin.H{"schema": gin.H{"type": "string"}}}},
+					"400": errorResponse("Invalid query parameter"),
+				},
+			}},
+			"/v1/tasks/bulk": gin.H{"post": gin.H{
+				"summary":     "Create several tasks atomically",
+				"parameters":  []gin.H{dryRunParam},
+				"requestBody": gin.H{"required": true, "content": jsonContent(taskList)},
+				"responses": gin.H{
+					"201": jsonResponse("Created", taskList),
+					"400": errorResponse("One or more tasks are invalid; nothing was created"),
+				},
+			}},
+			"/v1/tasks/batch": gin.H{"post": gin.H{
+				"summary":    "Set done on several tasks atomically",
+				"parameters": []gin.H{dryRunParam},
+				"requestBody": gin.H{"required": true, "content": jsonContent(gin.H{
+					"type":     "object",
+					"required": []string{"ids", "done"},
+					"properties": gin.H{
+						"ids":  gin.H{"type": "array", "items": gin.H{"type": "string"}},
+						"done": gin.H{"type": "boolean"},
+					},
+				})},
+				"responses": gin.H{
+					"200": jsonResponse("Number of tasks updated and the ids that weren't found", gin.H{
+						"type": "object",
+						"properties": gin.H{
+							"updated":   gin.H{"type": "integer"},
+							"not_found": gin.H{"type": "array", "items": gin.H{"type": "string"}},
+						},
+					}),
+					"400": errorResponse("Missing ids or done"),
+					"403": errorResponse("One of the tasks belongs to another user; nothing was updated"),
+				},
+			}},
+			"/v1/tasks/import": gin.H{"post": gin.H{
+				"summary":    "Import tasks from a CSV upload",
+				"parameters": []gin.H{dryRunParam},
+				"requestBody": gin.H{"required": true, "content": gin.H{"multipart/form-data": gin.H{"schema": gin.H{
+					"type":       "object",
+					"properties": gin.H{"file": gin.H{"type": "string", "format": "binary"}},
+				}}}},
+				"responses": gin.H{
+					"200": jsonResponse("Import summary with per-line errors", gin.H{
+						"type": "object",
+						"properties": gin.H{
+							"imported": gin.H{"type": "integer"},
+							"failed":   gin.H{"type": "integer"},
+							"errors":   gin.H{"type": "array", "items": gin.H{"type": "object"}},
+						},
+					}),
+					"400": errorResponse("Missing file or header"),
+					"413": errorResponse("Upload too large"),
+				},
+			}},
+			"/v1/tasks/stats": gin.H{"get": gin.H{
+				"summary":   "Count active tasks",
+				"responses": gin.H{"200": jsonResponse("Task counts", schemaFor(reflect.TypeOf(TaskStats{})))},
+			}},
+			"/v1/tasks/export": gin.H{"get": gin.H{
+				"summary":   "Download every task, trashed ones included, as a snapshot for /v1/tasks/restore",
+				"responses": gin.H{"200": jsonResponse("The snapshot", schemaFor(reflect.TypeOf(Snapshot{})))},
+			}},
+			"/v1/tasks/restore": gin.H{"post": gin.H{
+				"summary": "Replace every task with those of a snapshot, atomically",
+				"parameters": []gin.H{
+					query("confirm", "boolean", "Must be true; anything not in the snapshot is lost"),
+				},
+				"requestBody": gin.H{"required": true, "content": jsonContent(schemaFor(reflect.TypeOf(Snapshot{})))},
+				"responses": gin.H{
+					"200": jsonResponse("Number of tasks restored and removed", gin.H{
+						"type": "object",
+						"properties": gin.H{
+							"restored": gin.H{"type": "integer"},
+							"removed":  gin.H{"type": "integer"},
+						},
+					}),
+					"400": errorResponse("Missing confirm or an invalid snapshot; nothing was changed"),
+					"403": errorResponse("OWNER_ONLY_WRITES is set, or the snapshot holds more than MAX_TASKS active tasks"),
+					"413": errorResponse("Snapshot too large"),
+				},
+			}},
+			"/v1/tasks/stream": gin.H{"get": gin.H{
+				"summary": "Stream task changes as Server-Sent Events",
+				"responses": gin.H{"200": gin.H{
+					"description": "An event stream of TaskEvent payloads",
+					"content":     gin.H{"text/event-stream": gin.H{"schema": gin.H{"type": "string"}}},
+				}},
+			}},
+			"/v1/tasks/trash": gin.H{"get": gin.H{
+				"summary":    "List deleted tasks",
+				"parameters": append(listParams, envelopeParam),
+				"responses":  gin.H{"200": listResponse("A page of deleted tasks", taskList)},
+			}},
+			"/v1/tasks/completed": gin.H{"delete": gin.H{
+				"summary": "Move all done tasks to the trash",
+				"responses": gin.H{"200": jsonResponse("Number of tasks moved", gin.H{
+					"type": "object", "properties": gin.H{"deleted": gin.H{"type": "integer"}},
+				})},
+			}},
+			"/v1/tasks/{id}": gin.H{
+				"parameters": []gin.H{idParam},
+				"get": gin.H{
+					"summary":    "Fetch a task",
+					"parameters": []gin.H{fieldsParam},
+					"responses": gin.H{
+						"200": readResponse("The task", ref("Task")),
+						"304": gin.H{"description": "Not modified since the If-None-Match ETag"},
+						"404": errorResponse("Task not found"),
+					},
+				},
+				"put": gin.H{
+					"summary":     "Replace a task, creating it with this id if it doesn't exist",
+					"parameters":  writeHeaders,
+					"requestBody": gin.H{"required": true, "content": jsonContent(ref("Task"))},
+					"responses": gin.H{
+						"200": jsonResponse("Updated", ref("Task")),
+						"201": jsonResponse("Created with the id from the path", ref("Task")),
+						"400": errorResponse("Malformed body, or an id longer than 128 characters"),
+						"415": errorResponse("Body isn't JSON"),
+						"422": errorResponse("Invalid fields, all listed in details"),
+						"404": errorResponse("The task is in the trash"),
+						"409": errorResponse("Version conflict, or the task was created concurrently"),
+						"412": errorResponse("If-Match precondition failed"),
+					},
+				},
+				"patch": gin.H{
+					"summary":    "Partially update a task",
+					"parameters": writeHeaders,
+					"requestBody": gin.H{"required": true, "content": gin.H{
+						"application/json":   gin.H{"schema": ref("Task")},
+						jsonPatchContentType: gin.H{"schema": gin.H{"type": "array", "items": ref("JSONPatchOperation")}},
+					}},
+					"responses": gin.H{
+						"200": jsonResponse("Updated", ref("Task")),
+						"400": errorResponse("Malformed body, or a JSON Patch operation on a path that doesn't exist"),
+						"415": errorResponse("Body isn't JSON"),
+						"422": errorResponse("Invalid fields, or a JSON Patch changing a server-owned field, all listed in details"),
+						"404": errorResponse("Task not found"),
+						"409": errorResponse("Version conflict, or a JSON Patch test operation failed"),
+						"412": errorResponse("If-Match precondition failed"),
+					},
+				},
+				"delete": gin.H{
+					"summary":    "Move a task to the trash",
+					"parameters": []gin.H{query("hard", "boolean", "Delete permanently instead")},
+					"responses": gin.H{
+						"204": gin.H{"description": "Deleted"},
+						"404": errorResponse("Task not found"),
+					},
+				},
+			},
+			"/v1/tasks/{id}/toggle": gin.H{"post": gin.H{
+				"summary":    "Flip a task's done flag",
+				"parameters": append([]gin.H{idParam}, writeHeaders...),
+				"responses": gin.H{
+					"200": jsonResponse("Updated", ref("Task")),
+					"404": errorResponse("Task not found"),
+					"412": errorResponse("If-Match precondition failed"),
+				},
+			}},
+			"/v1/tasks/{id}/move": gin.H{"post": gin.H{
+				"summary":    "Move a task to a position in the manual order",
+				"parameters": []gin.H{idParam},
+				"requestBody": gin.H{"required": true, "content": jsonContent(gin.H{
+					"type":     "object",
+					"required": []string{"position"},
+					"properties": gin.H{
+						"position": gin.H{"type": "integer", "minimum": 0, "description": "0 is the top; past the end is the bottom"},
+					},
+				})},
+				"responses": gin.H{
+					"200": jsonResponse("The moved task", ref("Task")),
+					"400": errorResponse("Missing or negative position"),
+					"404": errorResponse("Task not found"),
+				},
+			}},
+			"/v1/tasks/{id}/subtasks": gin.H{"post": gin.H{
+				"summary":     "Add a subtask to the end of a task's checklist",
+				"parameters":  []gin.H{idParam},
+				"requestBody": gin.H{"required": true, "content": jsonContent(ref("Subtask"))},
+				"responses": gin.H{
+					"201": jsonResponse("Created", ref("Subtask")),
+					"400": errorResponse("Invalid subtask"),
+					"404": errorResponse("Task not found"),
+				},
+			}},
+			"/v1/tasks/{id}/subtasks/{subId}": gin.H{
+				"parameters": []gin.H{idParam, subIDParam},
+				"put": gin.H{
+					"summary":     "Replace a subtask",
+					"requestBody": gin.H{"required": true, "content": jsonContent(ref("Subtask"))},
+					"responses": gin.H{
+						"200": jsonResponse("Updated", ref("Subtask")),
+						"400": errorResponse("Invalid subtask"),
+						"404": errorResponse("Task or subtask not found"),
+					},
+				},
+				"delete": gin.H{
+					"summary": "Remove a subtask",
+					"responses": gin.H{
+						"204": gin.H{"description": "Deleted"},
+						"404": errorResponse("Task or subtask not found"),
+					},
+				},
+			},
+			"/v1/tasks/{id}/comments": gin.H{
+				"parameters": []gin.H{idParam},
+				"get": gin.H{
+					"summary": "List a task's comments, oldest first",
+					"responses": gin.H{
+						"200": jsonResponse("The comments", gin.H{"type": "array", "items": ref("Comment")}),
+						"404": errorResponse("Task not found"),
+					},
+				},
+				"post": gin.H{
+					"summary":     "Comment on a task",
+					"requestBody": gin.H{"required": true, "content": jsonContent(ref("Comment"))},
+					"responses": gin.H{
+						"201": jsonResponse("Created", ref("Comment")),
+						"400": errorResponse("Malformed body"),
+						"404": errorResponse("Task not found"),
+						"422": errorResponse("Empty or overlong comment body, listed in details like a task's fields"),
+					},
+				},
+			},
+			"/v1/tasks/{id}/comments/{commentId}": gin.H{"delete": gin.H{
+				"summary":    "Remove a comment",
+				"parameters": []gin.H{idParam, commentIDParam},
+				"responses": gin.H{
+					"204": gin.H{"description": "Deleted"},
+					"403": errorResponse("The comment was written by another user"),
+					"404": errorResponse("Task or comment not found"),
+				},
+			}},
+			"/v1/tasks/{id}/history": gin.H{"get": gin.H{
+				"summary": "List the field changes made to a task, oldest first",
+				"parameters": []gin.H{
+					idParam,
+					query("limit", "integer", "Page size (default 20 or DEFAULT_PAGE_LIMIT, max 100)"),
+					query("offset", "integer", "Number of changes to skip"),
+				},
+				"responses": gin.H{
+					"200": jsonResponse("A page of changes; the total is in X-Total-Count", gin.H{
+						"type": "array", "items": schemaFor(reflect.TypeOf(Change{})),
+					}),
+					"404": errorResponse("Task not found"),
+				},
+			}},
+			"/v1/audit": gin.H{"get": gin.H{
+				"summary": "List recorded task changes, oldest first",
+				"parameters": []gin.H{
+					query("task_id", "string", "Only changes to this task"),
+					query("limit", "integer", "Page size (default 20 or DEFAULT_PAGE_LIMIT, max 100)"),
+					query("offset", "integer", "Number of entries to skip"),
+				},
+				"responses": gin.H{"200": jsonResponse("A page of audit entries", gin.H{
+					"type": "array", "items": schemaFor(reflect.TypeOf(AuditEntry{})),
+				})},
+			}},
+			"/v1/tasks/undo": gin.H{"post": gin.H{
+				"summary": "Undo the caller's most recent change",
+				"responses": gin.H{
+					"200": jsonResponse("The audit entry undone and the task as it is now, null if undoing removed it", gin.H{
+						"type": "object",
+						"properties": gin.H{
+							"undone": schemaFor(reflect.TypeOf(AuditEntry{})),
+							"task":   ref("Task"),
+						},
+					}),
+					"404": errorResponse("Nothing to undo"),
+					"409": errorResponse("The task has been removed or recreated since"),
+				},
+			}},
+			"/v1/tasks/{id}/restore": gin.H{"post": gin.H{
+				"summary":    "Restore a task from the trash",
+				"parameters": []gin.H{idParam},
+				"responses": gin.H{
+					"200": jsonResponse("Restored", ref("Task")),
+					"404": errorResponse("Task not found in trash"),
+				},
+			}},
+		},
+		"components": gin.H{
+			"schemas": gin.H{
+				"Task":    taskSchema(),
+				"Subtask": subtaskSchema(),
+				"Comment": commentSchema(),
+				"TaskListEnvelope": gin.H{
+					"type": "object",
+					"properties": gin.H{
+						"data": taskList,
+						"meta": schemaFor(reflect.TypeOf(listMeta{})),
+					},
+				},
+				"JSONPatchOperation": gin.H{
+					"type":     "object",
+					"required": []string{"op", "path"},
+					"properties": gin.H{
+						"op":    gin.H{"type": "string", "enum": jsonPatchOps},
+						"path":  gin.H{"type": "string", "description": "JSON Pointer into the task, e.g. /tags/-"},
+						"value": gin.H{"description": "For add, replace and test"},
+					},
+				},
+				"Error": gin.H{
+					"type":       "object",
+					"properties": gin.H{"error": schemaFor(reflect.TypeOf(APIError{}))},
+				},
+			},
+			"securitySchemes": gin.H{
+				"bearerAuth": gin.H{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Task Service API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});</script>
+</body>
+</html>`
+
+// registerDocs serves the OpenAPI document and a Swagger UI page for it.
+func registerDocs(r gin.IRoutes) {
+	spec := openAPISpec()
+	r.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(200, spec)
+	})
+	r.GET("/docs", func(c *gin.Context) {
+		c.Data(200, "text/html; charset=utf-8", []byte(swaggerUIPage))
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// moveRequest is the body of POST /tasks/:id/move.
+type moveRequest struct {
+	// Position is the 0-based index the task should have among the active
+	// tasks in manual order; anything past the end moves it to the bottom.
+	Position *int `json:"position"`
+}
+
+// Move places an active task at a position in the manual order that lists
+// use by default, in one atomic change. Only the tasks whose order has to
+// change are updated: see newOrders.
+func (h *TaskHandler) Move(c *gin.Context) {
+	var req moveRequest
+	if err := bindJSON(c, &req); err != nil {
+		respondDecodeError(c, err)
+		return
+	}
+	if req.Position == nil || *req.Position < 0 {
+		respondError(c, 400, CodeValidationFailed, "position must be a non-negative integer")
+		return
+	}
+
+	h.moveMu.Lock()
+	defer h.moveMu.Unlock()
+	tasks, err := h.repo.List(c.Request.Context())
+	if err != nil {
+		respondError(c, 500, CodeInternal, err.Error())
+		return
+	}
+	tasks = filterTasks(tasks, func(t Task) bool { return t.DeletedAt == nil })
+	sortTasks(tasks, "order", false)
+	from := -1
+	for i, t := range tasks {
+		if t.ID == c.Param("id") {
+			from = i
+		}
+	}
+	if from < 0 {
+		respondError(c, 404, CodeTaskNotFound, "task not found")
+		return
+	}
+
+	moved := tasks[from]
+	tasks = append(tasks[:from], tasks[from+1:]...)
+	to := min(*req.Position, len(tasks))
+	tasks = append(tasks[:to], append([]Task{moved}, tasks[to:]...)...)
+
+	order := newOrders(tasks, moved.ID)
+	ids := make([]string, 0, len(order))
+	for _, t := range tasks {
+		if _, ok := order[t.ID]; ok {
+			ids = append(ids, t.ID)
+		}
+	}
+	if len(ids) > 0 {
+		now := time.Now().UTC()
+		updated, _, err := modifyManyActive(c.Request.Context(), h.asCaller(c), ids, func(t *Task) error {
+			t.Order = order[t.ID]
+			t.UpdatedAt = now
+			t.Version++
+			return nil
+		})
+		if err != nil {
+			respondModifyError(c, err)
+			return
+		}
+		for _, t := range updated {
+			if t.ID == moved.ID {
+				moved = t
+			}
+		}
+	}
+	setTaskETag(c, moved)
+	c.JSON(200, moved)
+}
+
+// newOrders returns the orders that have to change for tasks, in their new
+// manual order with the task movedID already in place, to sort that way. A
+// task keeps its order while it is above the one before it, and the moved
+// task while it also fits below the next, so a move touches the moved task
+// and the ones that must make room for it. Unplaced tasks after everything
+// that has an order stay unplaced, since they already sort last in the
+// order they were created.
+func newOrders(tasks []Task, movedID string) map[string]int {
+	last := -1
+	for i, t := range tasks {
+		if t.Order > 0 || t.ID == movedID {
+			last = i
+		}
+	}
+	order := map[string]int{}
+	prev := 0
+	for i, t := range tasks[:last+1] {
+		n := t.Order
+		if t.ID == movedID && i+1 < len(tasks) && tasks[i+1].Order > 0 && n >= tasks[i+1].Order {
+			n = 0
+		}
+		if n <= prev {
+			n = prev + 1
+			order[t.ID] = n
+		}
+		prev = n
+	}
+	return order
+}
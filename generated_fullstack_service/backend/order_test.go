@@ -0,0 +1,95 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestNewOrders(t *testing.T) {
+	cases := []struct {
+		name  string
+		order []int // current orders of the tasks, in their new manual order
+		moved int   // index of the moved task
+		want  map[string]int
+	}{
+		{"into a gap", []int{1, 7, 3, 5}, 1, map[string]int{"1": 2}},
+		{"to the top of a dense list", []int{4, 1, 2, 3}, 0, map[string]int{"0": 1, "1": 2, "2": 3, "3": 4}},
+		{"to the top with room", []int{9, 3, 4}, 0, map[string]int{"0": 1}},
+		{"to the bottom", []int{2, 3, 1}, 2, map[string]int{"2": 4}},
+		{"where it already is", []int{1, 2, 3}, 1, map[string]int{}},
+		{"among unplaced tasks", []int{1, 0, 0, 0}, 0, map[string]int{}},
+		{"below unplaced tasks", []int{0, 0, 0, 0}, 1, map[string]int{"0": 1, "1": 2}},
+		{"above later unplaced tasks", []int{1, 2, 0, 0}, 1, map[string]int{}},
+	}
+	for _, tc := range cases {
+		tasks := make([]Task, len(tc.order))
+		for i, o := range tc.order {
+			tasks[i] = Task{ID: strconv.Itoa(i), Order: o}
+		}
+		got := newOrders(tasks, strconv.Itoa(tc.moved))
+		if !maps.Equal(got, tc.want) {
+			t.Errorf("%s: newOrders(%v) = %v, want %v", tc.name, tc.order, got, tc.want)
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	ids := map[string]string{}
+	for _, title := range []string{"a", "b", "c", "d"} {
+		ids[title] = createTask(t, srv, `{"title":"`+title+`"}`).ID
+	}
+	list := func() ([]string, map[string]Task) {
+		_, b := request(t, srv, "GET", "/v1/tasks", "")
+		tasks := decode[[]Task](t, b)
+		titles := make([]string, len(tasks))
+		byTitle := map[string]Task{}
+		for i, task := range tasks {
+			titles[i] = task.Title
+			byTitle[task.Title] = task
+		}
+		return titles, byTitle
+	}
+	move := func(title string, position int) Task {
+		t.Helper()
+		resp, b := request(t, srv, "POST", "/v1/tasks/"+ids[title]+"/move", `{"position":`+strconv.Itoa(position)+`}`)
+		if resp.StatusCode != 200 {
+			t.Fatalf("move %s to %d: status %d: %s", title, position, resp.StatusCode, b)
+		}
+		return decode[Task](t, b)
+	}
+
+	move("c", 0)
+	if got, _ := list(); !slices.Equal(got, []string{"c", "a", "b", "d"}) {
+		t.Fatalf("after moving c to the top: %v", got)
+	}
+	move("a", 1)
+	move("b", 2)
+	move("d", 3)
+	if got, _ := list(); !slices.Equal(got, []string{"c", "a", "b", "d"}) {
+		t.Fatalf("after placing every task: %v", got)
+	}
+
+	_, before := list()
+	move("a", 100)
+	got, after := list()
+	if !slices.Equal(got, []string{"c", "b", "d", "a"}) {
+		t.Fatalf("after moving a to the bottom: %v", got)
+	}
+	for _, title := range []string{"c", "b", "d"} {
+		if after[title].Version != before[title].Version {
+			t.Errorf("moving a to the bottom bumped %s from version %d to %d", title, before[title].Version, after[title].Version)
+		}
+	}
+
+	_, before = list()
+	if moved := move("b", 1); moved.Version != before["b"].Version {
+		t.Errorf("moving b to where it is: version %d, want %d", moved.Version, before["b"].Version)
+	}
+
+	if resp, _ := request(t, srv, "POST", "/v1/tasks/missing/move", `{"position":0}`); resp.StatusCode != 404 {
+		t.Errorf("moving an unknown task: status %d, want 404", resp.StatusCode)
+	}
+}
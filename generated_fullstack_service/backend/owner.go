@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var errNotOwner = errors.New("task belongs to another user")
+
+// assignOwner makes the authenticated subject, if any, the owner of a new
+// task, overriding any owner in the request body.
+func assignOwner(t *Task, subject string) {
+	if subject != "" {
+		t.Owner = subject
+	}
+}
+
+// ownerGuard is a TaskRepository decorator for changes made by an
+// authenticated subject. A task's owner can't be changed by them, and with
+// ownerOnly set they can only change tasks they own. Tasks without an
+// owner, such as those created before auth was enabled, are open to all.
+type ownerGuard struct {
+	TaskRepository
+	subject   string
+	ownerOnly bool
+}
+
+func (g *ownerGuard) allowed(t Task) bool {
+	return !g.ownerOnly || t.Owner == "" || t.Owner == g.subject
+}
+
+func (g *ownerGuard) Modify(ctx context.Context, id string, fn func(*Task) error) (Task, bool, error) {
+	return g.TaskRepository.Modify(ctx, id, g.guard(fn))
+}
+
+func (g *ownerGuard) ModifyMany(ctx context.Context, ids []string, fn func(*Task) error) ([]Task, []string, error) {
+	return g.TaskRepository.ModifyMany(ctx, ids, g.guard(fn))
+}
+
+// guard wraps a Modify callback so it fails with errNotOwner on a task the
+// subject may not change and can't change the owner.
+func (g *ownerGuard) guard(fn func(*Task) error) func(*Task) error {
+	return func(t *Task) error {
+		if !g.allowed(*t) {
+			return errNotOwner
+		}
+		owner := t.Owner
+		if err := fn(t); err != nil {
+			return err
+		}
+		t.Owner = owner
+		return nil
+	}
+}
+
+func (g *ownerGuard) Update(ctx context.Context, id string, t Task) (bool, error) {
+	if err := g.check(ctx, id); err != nil {
+		return false, err
+	}
+	return g.TaskRepository.Update(ctx, id, t)
+}
+
+func (g *ownerGuard) Delete(ctx context.Context, id string) (bool, error) {
+	if err := g.check(ctx, id); err != nil {
+		return false, err
+	}
+	return g.TaskRepository.Delete(ctx, id)
+}
+
+// ReplaceAll is refused outright with ownerOnly set, since it would change
+// the tasks of every owner at once.
+func (g *ownerGuard) ReplaceAll(ctx context.Context, ts []Task) ([]Task, error) {
+	if g.ownerOnly {
+		return nil, errNotOwner
+	}
+	return g.TaskRepository.ReplaceAll(ctx, ts)
+}
+
+// check returns errNotOwner if the task exists and the subject may not
+// change it.
+func (g *ownerGuard) check(ctx context.Context, id string) error {
+	t, found, err := g.TaskRepository.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if found && !g.allowed(t) {
+		return errNotOwner
+	}
+	return nil
+}
+
+// TrashCompleted skips done tasks the subject may not change. With
+// ownerOnly set the tasks are trashed one at a time rather than in a single
+// store operation.
+func (g *ownerGuard) TrashCompleted(ctx context.Context, at time.Time) ([]Task, error) {
+	if !g.ownerOnly {
+		return g.TaskRepository.TrashCompleted(ctx, at)
+	}
+	tasks, err := g.TaskRepository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	trashed := []Task{}
+	for _, t := range tasks {
+		if !t.Done || t.DeletedAt != nil || !g.allowed(t) {
+			continue
+		}
+		t, found, err := modifyActive(ctx, g, t.ID, func(t *Task) error {
+			// The task may have changed since it was listed.
+			if !t.Done {
+				return errTrashSkipped
+			}
+			t.DeletedAt = &at
+			t.UpdatedAt = at
+			t.Version++
+			return nil
+		})
+		switch {
+		case errors.Is(err, errTrashSkipped), errors.Is(err, errNotOwner):
+		case err != nil:
+			return trashed, err
+		case found:
+			trashed = append(trashed, t)
+		}
+	}
+	return trashed, nil
+}
+
+var errTrashSkipped = errors.New("task is no longer done")
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	pgTaskPlaceholders = pgPlaceholders(1, len(taskColumnNames))
+	pgTaskAssignments  = pgAssignments(taskColumnNames)
+)
+
+// pgPlaceholders returns n numbered placeholders starting at $start.
+func pgPlaceholders(start, n int) string {
+	ps := make([]string, n)
+	for i := range ps {
+		ps[i] = fmt.Sprintf("$%d", start+i)
+	}
+	return strings.Join(ps, ", ")
+}
+
+func pgAssignments(cols []string) string {
+	as := make([]string, len(cols))
+	for i, c := range cols {
+		as[i] = fmt.Sprintf("%s = $%d", c, i+1)
+	}
+	return strings.Join(as, ", ")
+}
+
+// PostgresStore is a TaskRepository backed by a PostgreSQL connection pool.
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresStore connects to the database at url, which may be a URL or a
+// key/value DSN. Pool sizing can be tuned with pgx's pool_max_conns and
+// related parameters in url.
+func NewPostgresStore(ctx context.Context, url string) (*PostgresStore, error) {
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if err := runMigrations(ctx, pgMigrator{pool}, "migrations/postgres"); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &PostgresStore{pool: pool}, nil
+}
+
+// pgMigrationLock is the advisory lock key that serialises migrations
+// between instances starting against the same database.
+const pgMigrationLock = 0x7461736b // "task"
+
+// pgMigrator runs migrations on a PostgresStore's database.
+type pgMigrator struct {
+	pool *pgxpool.Pool
+}
+
+func (m pgMigrator) ensureTable(ctx context.Context) error {
+	_, err := m.pool.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	return err
+}
+
+func (m pgMigrator) apply(ctx context.Context, mig migration) (bool, error) {
+	tx, err := m.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, pgMigrationLock); err != nil {
+		return false, err
+	}
+	var applied bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, mig.version).Scan(&applied); err != nil {
+		return false, err
+	}
+	if applied {
+		return false, nil
+	}
+	// Without arguments Exec uses the simple protocol, which allows the
+	// several statements of a migration file.
+	if _, err := tx.Exec(ctx, mig.sql); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.version, mig.name); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// scanPGTask reads a row selected with taskColumns. Postgres hands back
+// timestamps in the session time zone; they are normalised to UTC to match
+// what the other stores return.
+func scanPGTask(row rowScanner) (Task, error) {
+	var t Task
+	if err := row.Scan(&t.ID, &t.Title, &t.Done, &t.CreatedAt, &t.UpdatedAt, &t.Version, &t.Priority, &t.DueDate, &t.DeletedAt, &t.Tags, &t.Subtasks, &t.Recurrence, &t.ParentID, &t.Owner, &t.Order, &t.Comments, &t.CompletionMode, &t.Archived); err != nil {
+		return Task{}, err
+	}
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	if t.Subtasks == nil {
+		t.Subtasks = []Subtask{}
+	}
+	if t.Comments == nil {
+		t.Comments = []Comment{}
+	}
+	t.CreatedAt = t.CreatedAt.UTC()
+	t.UpdatedAt = t.UpdatedAt.UTC()
+	t.DueDate = utcPtr(t.DueDate)
+	t.DeletedAt = utcPtr(t.DeletedAt)
+	return t, nil
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// pgTaskArgs returns t's column values in taskColumns order.
+func pgTaskArgs(t Task) []any {
+	tags := t.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	subtasks := t.Subtasks
+	if subtasks == nil {
+		subtasks = []Subtask{}
+	}
+	comments := t.Comments
+	if comments == nil {
+		comments = []Comment{}
+	}
+	return []any{t.ID, t.Title, t.Done, t.CreatedAt, t.UpdatedAt, t.Version, t.Priority, t.DueDate, t.DeletedAt, tags, subtasks, t.Recurrence, t.ParentID, t.Owner, t.Order, comments, t.CompletionMode, t.Archived}
+}
+
+func collectPGTasks(rows pgx.Rows) ([]Task, error) {
+	defer rows.Close()
+	tasks := []Task{}
+	for rows.Next() {
+		t, err := scanPGTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// Close releases every connection in the pool.
+func (s *PostgresStore) Close() error {
+	s.pool.Close()
+	return nil
+}
+
+// Ping checks that a connection can be acquired and the server answers.
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+// List returns all tasks in insertion order.
+func (s *PostgresStore) List(ctx context.Context) ([]Task, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+taskColumns+` FROM tasks ORDER BY seq`)
+	if err != nil {
+		return nil, err
+	}
+	return collectPGTasks(rows)
+}
+
+// Get returns the task with the given id and reports whether it was found.
+func (s *PostgresStore) Get(ctx context.Context, id string) (Task, bool, error) {
+	t, err := scanPGTask(s.pool.QueryRow(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Task{}, false, nil
+	}
+	if err != nil {
+		return Task{}, false, err
+	}
+	return t, true, nil
+}
+
+func (s *PostgresStore) Create(ctx context.Context, t Task) error {
+	_, err := s.pool.Exec(ctx, `INSERT INTO tasks (`+taskColumns+`) VALUES (`+pgTaskPlaceholders+`)`, pgTaskArgs(t)...)
+	return pgInsertError(err)
+}
+
+// pgUniqueViolation is the SQLSTATE of a unique constraint violation, which
+// on insert can only come from the primary key.
+const pgUniqueViolation = "23505"
+
+// pgUnavailable reports whether err means the server couldn't be reached or
+// isn't accepting connections yet, rather than that it answered and
+// rejected a statement or the credentials. SQLSTATE class 08 is a
+// connection exception and 57P03 is sent while the server starts up.
+func pgUnavailable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || pgErr.Code == "57P03"
+	}
+	var connErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connErr) || errors.As(err, &netErr)
+}
+
+// pgInsertError reports a primary key violation as errDuplicateID.
+func pgInsertError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return errDuplicateID
+	}
+	return err
+}
+
+// CreateMany inserts all of ts in a single transaction.
+func (s *PostgresStore) CreateMany(ctx context.Context, ts []Task) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, t := range ts {
+		batch.Queue(`INSERT INTO tasks (`+taskColumns+`) VALUES (`+pgTaskPlaceholders+`)`, pgTaskArgs(t)...)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return pgInsertError(err)
+	}
+	return tx.Commit(ctx)
+}
+
+// Update replaces the task with the given id and reports whether it was found.
+func (s *PostgresStore) Update(ctx context.Context, id string, t Task) (bool, error) {
+	args := append(pgTaskArgs(t), id)
+	tag, err := s.pool.Exec(ctx,
+		fmt.Sprintf(`UPDATE tasks SET %s WHERE id = $%d`, pgTaskAssignments, len(args)), args...)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Modify locks the row, applies fn and writes the result back in one
+// transaction. The returned task is read back from the database, so its
+// timestamps carry the precision Postgres stores them with.
+func (s *PostgresStore) Modify(ctx context.Context, id string, fn func(*Task) error) (Task, bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Task{}, false, err
+	}
+	defer tx.Rollback(ctx)
+
+	t, err := scanPGTask(tx.QueryRow(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = $1 FOR UPDATE`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Task{}, false, nil
+	}
+	if err != nil {
+		return Task{}, false, err
+	}
+	if err := fn(&t); err != nil {
+		return Task{}, true, err
+	}
+	args := append(pgTaskArgs(t), id)
+	t, err = scanPGTask(tx.QueryRow(ctx,
+		fmt.Sprintf(`UPDATE tasks SET %s WHERE id = $%d RETURNING %s`, pgTaskAssignments, len(args), taskColumns), args...))
+	if err != nil {
+		return Task{}, true, err
+	}
+	return t, true, tx.Commit(ctx)
+}
+
+// Delete removes the task with the given id and reports whether it was found.
+func (s *PostgresStore) Delete(ctx context.Context, id string) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM tasks WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ModifyMany locks all of the tasks up front, in id order so concurrent
+// batches can't deadlock, and applies fn to them within one transaction.
+func (s *PostgresStore) ModifyMany(ctx context.Context, ids []string, fn func(*Task) error) ([]Task, []string, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = ANY($1) ORDER BY id FOR UPDATE`, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	locked := map[string]Task{}
+	for rows.Next() {
+		t, err := scanPGTask(rows)
+		if err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		locked[t.ID] = t
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	modified, missing := []Task{}, []string{}
+	for _, id := range ids {
+		t, found := locked[id]
+		if !found {
+			missing = append(missing, id)
+			continue
+		}
+		err := fn(&t)
+		if errors.Is(err, errSkip) {
+			missing = append(missing, id)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		args := append(pgTaskArgs(t), id)
+		t, err = scanPGTask(tx.QueryRow(ctx,
+			fmt.Sprintf(`UPDATE tasks SET %s WHERE id = $%d RETURNING %s`, pgTaskAssignments, len(args), taskColumns), args...))
+		if err != nil {
+			return nil, nil, err
+		}
+		modified = append(modified, t)
+	}
+	return modified, missing, tx.Commit(ctx)
+}
+
+// TrashCompleted moves every done, active task to the trash and returns the
+// tasks as they are after the move.
+func (s *PostgresStore) TrashCompleted(ctx context.Context, at time.Time) ([]Task, error) {
+	rows, err := s.pool.Query(ctx, `UPDATE tasks SET deleted_at = $1, updated_at = $1, version = version + 1
+		WHERE done AND deleted_at IS NULL RETURNING `+taskColumns, at)
+	if err != nil {
+		return nil, err
+	}
+	return collectPGTasks(rows)
+}
+
+// ReplaceAll deletes every task and inserts ts in a single transaction.
+func (s *PostgresStore) ReplaceAll(ctx context.Context, ts []Task) ([]Task, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `DELETE FROM tasks RETURNING `+taskColumns)
+	if err != nil {
+		return nil, err
+	}
+	removed, err := collectPGTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	batch := &pgx.Batch{}
+	for _, t := range ts {
+		batch.Queue(`INSERT INTO tasks (`+taskColumns+`) VALUES (`+pgTaskPlaceholders+`)`, pgTaskArgs(t)...)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, pgInsertError(err)
+	}
+	return removed, tx.Commit(ctx)
+}
+
+// Stats streams the active tasks through TaskStats.add.
+func (s *PostgresStore) Stats(ctx context.Context, now time.Time) (TaskStats, error) {
+	stats := newTaskStats()
+	rows, err := s.pool.Query(ctx, `SELECT `+taskColumns+` FROM tasks WHERE deleted_at IS NULL`)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t, err := scanPGTask(rows)
+		if err != nil {
+			return stats, err
+		}
+		stats.add(t, now)
+	}
+	return stats, rows.Err()
+}
+
+func (s *PostgresStore) AppendAudit(e AuditEntry) error {
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return err
+	}
+	_, err = s.pool.Exec(context.Background(), `INSERT INTO audit_log (at, subject, action, task_id, changes, undoes) VALUES ($1, $2, $3, $4, $5, $6)`,
+		e.At, e.Subject, e.Action, e.TaskID, changes, e.Undoes)
+	return err
+}
+
+func scanPGAuditEntry(row rowScanner) (AuditEntry, error) {
+	var e AuditEntry
+	var changes []byte
+	if err := row.Scan(&e.ID, &e.At, &e.Subject, &e.Action, &e.TaskID, &changes, &e.Undoes); err != nil {
+		return e, err
+	}
+	e.At = e.At.UTC()
+	return e, json.Unmarshal(changes, &e.Changes)
+}
+
+func (s *PostgresStore) LastUndoable(subject string) (AuditEntry, bool, error) {
+	e, err := scanPGAuditEntry(s.pool.QueryRow(context.Background(), `SELECT `+auditEntryColumns+` FROM audit_log a
+		WHERE subject = $1 AND undoes = 0 AND NOT EXISTS (SELECT 1 FROM audit_log u WHERE u.undoes = a.id)
+		ORDER BY id DESC LIMIT 1`, subject))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return AuditEntry{}, false, nil
+	}
+	if err != nil {
+		return AuditEntry{}, false, err
+	}
+	return e, true, nil
+}
+
+func (s *PostgresStore) ListAudit(taskID string, limit, offset int) ([]AuditEntry, int, error) {
+	ctx := context.Background()
+	// An empty taskID matches every entry.
+	const where = ` WHERE $1::text = '' OR task_id = $1`
+	var total int
+	if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM audit_log`+where, taskID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.pool.Query(ctx, `SELECT `+auditEntryColumns+` FROM audit_log`+where+` ORDER BY id LIMIT $2 OFFSET $3`,
+		taskID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	entries := []AuditEntry{}
+	for rows.Next() {
+		e, err := scanPGAuditEntry(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
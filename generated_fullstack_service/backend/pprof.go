@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http/pprof"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pprofLongLived are the profile endpoints that collect for a while (30s by
+// default) before answering, so they must not be timed out or buffered.
+var pprofLongLived = []string{"/debug/pprof/profile", "/debug/pprof/trace"}
+
+// registerPprof serves the net/http/pprof handlers under /debug/pprof,
+// behind the given middleware.
+func registerPprof(r gin.IRouter, middleware ...gin.HandlerFunc) {
+	g := r.Group("/debug/pprof", middleware...)
+	index := gin.WrapF(pprof.Index)
+	g.GET("/*name", func(c *gin.Context) {
+		switch strings.TrimPrefix(c.Param("name"), "/") {
+		case "cmdline":
+			pprof.Cmdline(c.Writer, c.Request)
+		case "profile":
+			pprof.Profile(c.Writer, c.Request)
+		case "symbol":
+			pprof.Symbol(c.Writer, c.Request)
+		case "trace":
+			pprof.Trace(c.Writer, c.Request)
+		default:
+			// Index also serves the named profiles, such as heap.
+			index(c)
+		}
+	})
+	// go tool pprof looks up symbols with a POST.
+	g.POST("/symbol", gin.WrapF(pprof.Symbol))
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitIdleTTL is how long a client's bucket survives without requests
+// before it is dropped. A forgotten bucket just means the client starts again
+// with a full burst.
+const rateLimitIdleTTL = 10 * time.Minute
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// ipRateLimiter holds a token bucket per client IP. An rps of 0 lets every
+// request through.
+type ipRateLimiter struct {
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	rps       rate.Limit
+	burst     int
+	lastSweep time.Time
+}
+
+func newIPRateLimiter(rps, burst int) *ipRateLimiter {
+	return &ipRateLimiter{
+		clients:   make(map[string]*clientLimiter),
+		rps:       rate.Limit(rps),
+		burst:     burst,
+		lastSweep: time.Now(),
+	}
+}
+
+// set switches to a new rate; every client starts again with a full
+// bucket.
+func (l *ipRateLimiter) set(rps, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clients = make(map[string]*clientLimiter)
+	l.rps, l.burst = rate.Limit(rps), burst
+}
+
+// get returns the bucket for ip, creating it if needed, or nil when limiting
+// is off. Idle buckets are swept at most once per TTL so the map stays
+// bounded by recently active clients.
+func (l *ipRateLimiter) get(ip string, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rps == 0 {
+		return nil
+	}
+
+	if now.Sub(l.lastSweep) > rateLimitIdleTTL {
+		for k, cl := range l.clients {
+			if now.Sub(cl.lastSeen) > rateLimitIdleTTL {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	cl, ok := l.clients[ip]
+	if !ok {
+		cl = &clientLimiter{limiter: rate.NewLimiter(l.rps, l.burst)}
+		l.clients[ip] = cl
+	}
+	cl.lastSeen = now
+	return cl.limiter
+}
+
+// RateLimit allows each client IP the requests per second l is set to, with
+// its bursts, answering 429 with a Retry-After header once the bucket is
+// empty.
+func RateLimit(l *ipRateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		limiter := l.get(c.ClientIP(), now)
+		if limiter == nil {
+			c.Next()
+			return
+		}
+		res := limiter.ReserveN(now, 1)
+		if delay := res.DelayFrom(now); delay > 0 {
+			res.CancelAt(now)
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			respondError(c, 429, CodeRateLimited, "rate limit exceeded")
+			return
+		}
+		c.Next()
+	}
+}
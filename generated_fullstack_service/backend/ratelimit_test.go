@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+)
+
+func rateLimitedConfig(trusted ...string) Config {
+	cfg := testConfig()
+	cfg.RateLimit = RateLimitConfig{RPS: 1, Burst: 1}
+	cfg.TrustedProxies = trusted
+	return cfg
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	srv := newTestServer(t, rateLimitedConfig())
+
+	if resp, b := request(t, srv, "GET", "/v1/tasks", "", "X-Forwarded-For", "203.0.113.1"); resp.StatusCode != 200 {
+		t.Fatalf("first request: status %d: %s", resp.StatusCode, b)
+	}
+	resp, b := request(t, srv, "GET", "/v1/tasks", "", "X-Forwarded-For", "203.0.113.2")
+	if resp.StatusCode != 429 {
+		t.Fatalf("second request with another X-Forwarded-For: status %d, want 429", resp.StatusCode)
+	}
+	if code := errorCode(t, b); code != CodeRateLimited {
+		t.Errorf("code = %q, want %q", code, CodeRateLimited)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("429 without Retry-After")
+	}
+}
+
+func TestRateLimitHonoursTrustedProxy(t *testing.T) {
+	srv := newTestServer(t, rateLimitedConfig("127.0.0.1", "::1"))
+
+	for _, ip := range []string{"203.0.113.1", "203.0.113.2"} {
+		if resp, b := request(t, srv, "GET", "/v1/tasks", "", "X-Forwarded-For", ip); resp.StatusCode != 200 {
+			t.Fatalf("first request from %s: status %d: %s", ip, resp.StatusCode, b)
+		}
+	}
+	if resp, _ := request(t, srv, "GET", "/v1/tasks", "", "X-Forwarded-For", "203.0.113.1"); resp.StatusCode != 429 {
+		t.Errorf("second request from 203.0.113.1: status %d, want 429", resp.StatusCode)
+	}
+}
+
+func TestValidateTrustedProxies(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.1", "::1"}
+	if err := cfg.validate(); err != nil {
+		t.Errorf("valid entries: %v", err)
+	}
+	cfg.TrustedProxies = []string{"proxy.internal"}
+	if err := cfg.validate(); err == nil {
+		t.Error("a host name was accepted")
+	}
+}
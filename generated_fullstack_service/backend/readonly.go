@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readOnlyRetryAfter is the Retry-After, in seconds, sent with writes refused
+// in read-only mode. Maintenance rarely ends on cue, so it is only a hint.
+const readOnlyRetryAfter = 60
+
+// ReadOnlyMode says whether the server is refusing writes. It starts as
+// configured by READ_ONLY and can be switched at runtime through
+// /v1/admin/read-only. The zero value, and a nil mode, allow writes.
+type ReadOnlyMode struct {
+	on atomic.Bool
+}
+
+// Enabled reports whether writes are refused.
+func (m *ReadOnlyMode) Enabled() bool {
+	return m != nil && m.on.Load()
+}
+
+// Set turns read-only mode on or off.
+func (m *ReadOnlyMode) Set(on bool) {
+	m.on.Store(on)
+}
+
+// ReadOnly answers every request other than GET, HEAD and OPTIONS with 503
+// and a Retry-After header while mode is enabled, so maintenance can keep
+// reads going without each handler checking for it. Excluded paths carry
+// reads in POST bodies and refuse writes themselves, with refuseReadOnly.
+func ReadOnly(mode *ReadOnlyMode, excludedPaths ...string) gin.HandlerFunc {
+	excluded := make(map[string]bool, len(excludedPaths))
+	for _, p := range excludedPaths {
+		excluded[p] = true
+	}
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case "GET", "HEAD", "OPTIONS":
+		default:
+			if !excluded[c.Request.URL.Path] && refuseReadOnly(c, mode) {
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// refuseReadOnly answers 503 and reports true if mode is enabled.
+func refuseReadOnly(c *gin.Context, mode *ReadOnlyMode) bool {
+	if !mode.Enabled() {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(readOnlyRetryAfter))
+	respondError(c, 503, CodeReadOnly, "the server is in read-only mode; try again later")
+	return true
+}
+
+// registerReadOnly serves GET and PUT /v1/admin/read-only, which report and
+// switch mode, behind AdminToken like the other admin routes. They sit
+// outside the routes ReadOnly guards, or read-only mode could never be
+// turned off.
+func registerReadOnly(r gin.IRouter, mode *ReadOnlyMode, token string) {
+	r.GET(adminReadOnlyPath, AdminToken(token), func(c *gin.Context) {
+		c.JSON(200, gin.H{"read_only": mode.Enabled()})
+	})
+	r.PUT(adminReadOnlyPath, AdminToken(token), func(c *gin.Context) {
+		var body struct {
+			ReadOnly *bool `json:"read_only"`
+		}
+		if err := bindJSON(c, &body); err != nil {
+			respondDecodeError(c, err)
+			return
+		}
+		if body.ReadOnly == nil {
+			respondError(c, 400, CodeInvalidRequest, "read_only is required")
+			return
+		}
+		mode.Set(*body.ReadOnly)
+		slog.Warn("read-only mode switched", "read_only", *body.ReadOnly, "subject", c.GetString(subjectKey), "request_id", c.GetString(requestIDKey))
+		c.JSON(200, gin.H{"read_only": *body.ReadOnly})
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadOnlySwitchNeedsAdminToken(t *testing.T) {
+	cfg := testConfig()
+	cfg.ReadOnly = true
+	cfg.JWTSecret = "test-secret"
+	cfg.AdminToken = "admin"
+	srv := newTestServer(t, cfg)
+	token := testToken(t, cfg.JWTSecret, "alice")
+
+	for _, headers := range [][]string{nil, {"Authorization", token}, {adminTokenHeader, "wrong"}} {
+		if resp, _ := request(t, srv, "PUT", adminReadOnlyPath, `{"read_only":false}`, headers...); resp.StatusCode != 404 {
+			t.Errorf("PUT with %q: status %d, want 404", headers, resp.StatusCode)
+		}
+	}
+	if resp, _ := request(t, srv, "POST", "/v1/tasks", `{"title":"Blocked"}`, "Authorization", token); resp.StatusCode != 503 {
+		t.Fatalf("read-only mode was switched off without the admin token: status %d", resp.StatusCode)
+	}
+
+	resp, b := request(t, srv, "PUT", adminReadOnlyPath, `{"read_only":false}`, adminTokenHeader, "admin")
+	if resp.StatusCode != 200 {
+		t.Fatalf("PUT with the admin token: status %d: %s", resp.StatusCode, b)
+	}
+	if resp, _ := request(t, srv, "POST", "/v1/tasks", `{"title":"Allowed"}`, "Authorization", token); resp.StatusCode != 201 {
+		t.Errorf("create after switching read-only off: status %d, want 201", resp.StatusCode)
+	}
+	if resp, b := request(t, srv, "GET", adminReadOnlyPath, "", adminTokenHeader, "admin"); resp.StatusCode != 200 || !strings.Contains(string(b), `"read_only":false`) {
+		t.Errorf("GET with the admin token: status %d: %s", resp.StatusCode, b)
+	}
+}
+
+func TestReadOnlySwitchWithoutAdminToken(t *testing.T) {
+	cfg := testConfig()
+	cfg.ReadOnly = true
+	srv := newTestServer(t, cfg)
+	if resp, _ := request(t, srv, "PUT", adminReadOnlyPath, `{"read_only":false}`); resp.StatusCode != 404 {
+		t.Errorf("PUT without ADMIN_TOKEN set: status %d, want 404", resp.StatusCode)
+	}
+	if resp, _ := request(t, srv, "POST", "/v1/tasks", `{"title":"Blocked"}`); resp.StatusCode != 503 {
+		t.Errorf("create in read-only mode: status %d, want 503", resp.StatusCode)
+	}
+}
+
+func TestReadOnlyGraphQL(t *testing.T) {
+	cfg := testConfig()
+	cfg.ReadOnly = true
+	srv := newTestServer(t, cfg)
+
+	for _, query := range []string{
+		`{"query":"{ tasks { id } }"}`,
+		`{"query":"query List { tasks { id } } mutation Add { createTask(input: {title: \"x\"}) { id } }","operationName":"List"}`,
+	} {
+		resp, b := request(t, srv, "POST", graphQLPath, query)
+		if resp.StatusCode != 200 || strings.Contains(string(b), `"errors"`) {
+			t.Errorf("query %s in read-only mode: status %d: %s", query, resp.StatusCode, b)
+		}
+	}
+	for _, query := range []string{
+		`{"query":"mutation { createTask(input: {title: \"x\"}) { id } }"}`,
+		`{"query":"query List { tasks { id } } mutation Add { createTask(input: {title: \"x\"}) { id } }","operationName":"Add"}`,
+	} {
+		resp, b := request(t, srv, "POST", graphQLPath, query)
+		if resp.StatusCode != 503 || errorCode(t, b) != CodeReadOnly || resp.Header.Get("Retry-After") == "" {
+			t.Errorf("mutation %s in read-only mode: status %d: %s", query, resp.StatusCode, b)
+		}
+	}
+}
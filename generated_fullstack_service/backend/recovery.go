@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery turns a panic in a handler into a 500 with the usual APIError
+// body, logging the panic and stack with the request id. With exposeStack
+// set, meant for development only, the response details carry them too.
+func Recovery(exposeStack bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				// net/http uses this to abort a response deliberately.
+				panic(p)
+			}
+			stack := string(debug.Stack())
+			slog.Default().LogAttrs(c.Request.Context(), slog.LevelError, "panic",
+				slog.String("method", c.Request.Method),
+				slog.String("path", c.Request.URL.Path),
+				slog.String("request_id", c.GetString(requestIDKey)),
+				slog.String("panic", fmt.Sprint(p)),
+				slog.String("stack", stack),
+			)
+			if err, ok := p.(error); ok && isBrokenConnection(err) {
+				// The client is gone; there is nobody to answer.
+				c.Abort()
+				return
+			}
+			if c.Writer.Written() {
+				// Too late for an error body; cut the response short.
+				c.Abort()
+				return
+			}
+			var details any
+			if exposeStack {
+				details = gin.H{"panic": fmt.Sprint(p), "stack": stack}
+			}
+			respondErrorDetails(c, 500, CodeInternal, "internal server error", details)
+		}()
+		c.Next()
+	}
+}
+
+func isBrokenConnection(err error) bool {
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func isRecurrence(r string) bool {
+	for _, v := range recurrences {
+		if v == r {
+			return true
+		}
+	}
+	return false
+}
+
+// nextDue returns when the occurrence after one due at due is due.
+func nextDue(due time.Time, recurrence string) time.Time {
+	switch recurrence {
+	case RecurrenceDaily:
+		return due.AddDate(0, 0, 1)
+	case RecurrenceWeekly:
+		return due.AddDate(0, 0, 7)
+	case RecurrenceMonthly:
+		// Stay within the next month: a task due on the 31st recurs on the
+		// last day of shorter months rather than spilling into the one
+		// after.
+		next := due.AddDate(0, 1, 0)
+		if next.Day() != due.Day() {
+			next = next.AddDate(0, 0, -next.Day())
+		}
+		return next
+	}
+	return due
+}
+
+// nextOccurrence returns the task that follows t, which recurs at the given
+// interval and has just been completed at now. Its due date is advanced by
+// one interval from t's, or from now if t had none.
+func nextOccurrence(t Task, recurrence string, now time.Time) Task {
+	due := now
+	if t.DueDate != nil {
+		due = *t.DueDate
+	}
+	due = nextDue(due, recurrence)
+
+	next := t
+	next.Recurrence = recurrence
+	next.ID = uuid.NewString()
+	next.Done = false
+	next.DueDate = &due
+	next.CreatedAt, next.UpdatedAt = now, now
+	next.Version = 1
+	next.DeletedAt = nil
+	if next.ParentID == "" {
+		next.ParentID = t.ID
+	}
+	next.Tags = append([]string{}, t.Tags...)
+	next.Subtasks = make([]Subtask, len(t.Subtasks))
+	for i, s := range t.Subtasks {
+		s.Done = false
+		next.Subtasks[i] = s
+	}
+	next.Comments = []Comment{}
+	return next
+}
+
+// recurringStore is a TaskRepository decorator that creates the next
+// occurrence whenever a change through Modify completes a recurring task.
+// The recurrence moves to the new occurrence, so completing the same task
+// again after reopening it doesn't schedule a second one.
+type recurringStore struct {
+	TaskRepository
+}
+
+func (s *recurringStore) Modify(ctx context.Context, id string, fn func(*Task) error) (Task, bool, error) {
+	recurrence := RecurrenceNone
+	t, found, err := s.TaskRepository.Modify(ctx, id, func(t *Task) error {
+		var err error
+		recurrence, err = takeRecurrence(t, fn)
+		return err
+	})
+	if err != nil || !found {
+		return t, found, err
+	}
+	s.scheduleNext(ctx, t, recurrence)
+	return t, true, nil
+}
+
+func (s *recurringStore) ModifyMany(ctx context.Context, ids []string, fn func(*Task) error) ([]Task, []string, error) {
+	recurrences := map[string]string{}
+	modified, missing, err := s.TaskRepository.ModifyMany(ctx, ids, func(t *Task) error {
+		recurrence, err := takeRecurrence(t, fn)
+		recurrences[t.ID] = recurrence
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, t := range modified {
+		s.scheduleNext(ctx, t, recurrences[t.ID])
+	}
+	return modified, missing, nil
+}
+
+// takeRecurrence applies fn to t and, if that completes it, moves its
+// recurrence out of the task and returns it.
+func takeRecurrence(t *Task, fn func(*Task) error) (string, error) {
+	wasDone := t.Done
+	if err := fn(t); err != nil {
+		return RecurrenceNone, err
+	}
+	recurrence := RecurrenceNone
+	if !wasDone && t.Done && t.DeletedAt == nil {
+		recurrence, t.Recurrence = t.Recurrence, RecurrenceNone
+	}
+	return recurrence, nil
+}
+
+// scheduleNext creates the next occurrence of the just completed task t
+// unless recurrence is RecurrenceNone.
+func (s *recurringStore) scheduleNext(ctx context.Context, t Task, recurrence string) {
+	if recurrence == RecurrenceNone {
+		return
+	}
+	next := nextOccurrence(t, recurrence, time.Now().UTC())
+	// The completion is already stored, so a failure here can only be
+	// reported.
+	if err := s.TaskRepository.Create(ctx, next); err != nil {
+		log.Printf("recurrence: create next occurrence of task %s: %v", t.ID, err)
+		return
+	}
+	// A dry run's create went nowhere.
+	if !isDryRun(ctx) {
+		tasksGauge.Inc()
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDryRunCompletionOfRecurringTaskKeepsGauge(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	task := createTask(t, srv, `{"title":"Water plants","recurrence":"daily"}`)
+	body := `{"ids":["` + task.ID + `"],"done":true}`
+
+	before := testutil.ToFloat64(tasksGauge)
+	if resp, b := request(t, srv, "POST", "/v1/tasks/batch?dry_run=true", body); resp.StatusCode != 200 {
+		t.Fatalf("dry-run batch: status %d: %s", resp.StatusCode, b)
+	}
+	if got := testutil.ToFloat64(tasksGauge); got != before {
+		t.Errorf("dry-run completion moved the tasks gauge from %v to %v", before, got)
+	}
+	_, b := request(t, srv, "GET", "/v1/tasks", "")
+	if tasks := decode[[]Task](t, b); len(tasks) != 1 {
+		t.Fatalf("dry run stored tasks: %s", b)
+	}
+
+	if resp, b := request(t, srv, "POST", "/v1/tasks/batch", body); resp.StatusCode != 200 {
+		t.Fatalf("batch: status %d: %s", resp.StatusCode, b)
+	}
+	if got := testutil.ToFloat64(tasksGauge); got != before+1 {
+		t.Errorf("completing for real: gauge %v, want %v for the next occurrence", got, before+1)
+	}
+}
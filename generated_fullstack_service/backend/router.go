@@ -0,0 +1,116 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// longLivedPaths are the streaming routes and slow profiles, which must not
+// be buffered or timed out.
+var longLivedPaths = append([]string{"/v1/tasks/stream", "/v1/ws", "/tasks/stream", "/ws"}, pprofLongLived...)
+
+// NewRouter registers every middleware and route, configured by cfg, on a
+// new engine serving store. Only the request timeout, which wraps the
+// engine, is left to the caller, as is running the returned handler's
+// background work: idempotency cleanup and webhook delivery. Tests can serve
+// the engine with httptest.NewServer.
+func NewRouter(store Store, cfg Config) (*gin.Engine, *TaskHandler) {
+	tasks := NewTaskHandler(store)
+	tasks.importMaxBytes = cfg.ImportMaxBytes
+	tasks.pageLimit = cfg.DefaultPageLimit
+	tasks.ownerOnlyWrites = cfg.OwnerOnlyWrites
+	tasks.dedup = cfg.Dedup
+	tasks.warnDuplicates = cfg.DuplicateWarning
+	tasks.maxTasks = cfg.MaxTasks
+	tasks.eventFormat = cfg.EventFormat
+	tasks.readOnly = &ReadOnlyMode{}
+	tasks.readOnly.Set(cfg.ReadOnly)
+
+	r := gin.New()
+	// validate has checked every entry, and nil trusts no proxy at all.
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Printf("trusted proxies: %v", err)
+	}
+	r.Use(RequestID())
+	r.Use(Tracing())
+	r.Use(StructuredLogger())
+	if cfg.SlowRequestMS > 0 {
+		r.Use(SlowRequestLog(time.Duration(cfg.SlowRequestMS)*time.Millisecond, longLivedPaths...))
+	}
+	r.Use(Recovery(cfg.DevMode))
+	live := newLiveConfig(cfg)
+	r.Use(CORSMiddleware(live.cors))
+	if cfg.BasicAuth.Enabled() {
+		r.Use(BasicAuth(cfg.BasicAuth.User, cfg.BasicAuth.Password, "/health"))
+	}
+	r.Use(Metrics())
+	// Uploads and snapshots are capped separately by IMPORT_MAX_BYTES.
+	r.Use(BodySizeLimit(cfg.MaxBodyBytes, "/v1/tasks/import", "/tasks/import", "/v1/tasks/restore", "/tasks/restore"))
+	// promhttp negotiates its own compression for /metrics, the event
+	// stream has to be flushed message by message, and /ws is hijacked.
+	r.Use(Gzip(gzipMinSize, append([]string{"/metrics"}, longLivedPaths...)...))
+
+	r.NoRoute(func(c *gin.Context) {
+		respondError(c, 404, CodeRouteNotFound, "no route for "+c.Request.Method+" "+c.Request.URL.Path)
+	})
+
+	registerHealth(r, store)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	registerVersion(r)
+	registerDocs(r)
+
+	// Reads stay public; writes require a token when JWT_SECRET is set.
+	var writeAuth []gin.HandlerFunc
+	if cfg.JWTSecret != "" {
+		writeAuth = append(writeAuth, JWTAuthMiddleware(cfg.JWTSecret))
+	} else if !cfg.BasicAuth.Enabled() {
+		log.Println("no JWT secret or basic auth configured; write endpoints are unauthenticated")
+	}
+	if cfg.EnablePprof {
+		registerPprof(r, writeAuth...)
+		log.Println("pprof enabled under /debug/pprof")
+	}
+	if cfg.AdminToken != "" {
+		tasks.registerReset(r, cfg.AdminToken)
+		registerConfig(r, live, cfg.AdminToken)
+		registerReadOnly(r, tasks.readOnly, cfg.AdminToken)
+		log.Println("admin token set; POST " + adminResetPath + " can wipe the store")
+	}
+	switch {
+	case cfg.ReadOnly && cfg.AdminToken != "":
+		log.Println("starting in read-only mode; writes get 503 until PUT " + adminReadOnlyPath + " turns it off")
+	case cfg.ReadOnly:
+		log.Println("starting in read-only mode; writes get 503 until a restart without READ_ONLY")
+	}
+	// Probes, metrics, docs, pprof and the admin routes above stay exempt
+	// from rate limiting and read-only mode.
+	api := r.Group("/")
+	// Installed even when off, so a config reload can turn it on.
+	api.Use(RateLimit(live.rateLimit))
+	// GraphQL queries are reads sent by POST; the handler refuses
+	// mutations itself.
+	api.Use(ReadOnly(tasks.readOnly, graphQLPath))
+	// CSV uploads are multipart; ImportCSV checks them itself.
+	api.Use(JSONContentType(cfg.RequireContentType, "/v1/tasks/import", "/tasks/import"))
+	v1 := api.Group("/v1")
+	tasks.RegisterRoutes(v1, v1.Group("/", writeAuth...))
+	// GraphQL isn't versioned by path. Queries sit behind the write auth
+	// too, since they share the endpoint with mutations.
+	tasks.registerGraphQL(api, cfg.DevMode, writeAuth...)
+	// The unprefixed routes predate /v1 and will be removed in the next
+	// release.
+	legacy := api.Group("/", Deprecated("/v1"))
+	tasks.RegisterRoutes(legacy, legacy.Group("/", writeAuth...))
+	registerOptions(r)
+	return r, tasks
+}
+
+// getAndHead registers handler for both GET and HEAD on path. net/http drops
+// the body of a HEAD response, leaving the status and headers GET would send.
+func getAndHead(r gin.IRoutes, path string, handler gin.HandlerFunc) {
+	r.GET(path, handler)
+	r.HEAD(path, handler)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	log.SetOutput(io.Discard)
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// testConfig is the default configuration without rate limiting, which a
+// test making many requests would otherwise run into.
+func testConfig() Config {
+	cfg := defaultConfig()
+	cfg.RateLimit.RPS = 0
+	return cfg
+}
+
+// newTestServer serves NewRouter over an in-memory store for the length of
+// the test.
+func newTestServer(t *testing.T, cfg Config) *httptest.Server {
+	t.Helper()
+	r, _ := NewRouter(NewTaskStore(), cfg)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// request sends body, if not empty, as JSON to srv and returns the response
+// with its body read. Extra headers are given as name, value pairs.
+func request(t *testing.T, srv *httptest.Server, method, path, body string, headers ...string) (*http.Response, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, srv.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, b
+}
+
+// decode unmarshals a response body into a value of type T.
+func decode[T any](t *testing.T, b []byte) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatalf("decode %s: %v", b, err)
+	}
+	return v
+}
+
+// createTask POSTs body to /v1/tasks and returns the created task.
+func createTask(t *testing.T, srv *httptest.Server, body string) Task {
+	t.Helper()
+	resp, b := request(t, srv, "POST", "/v1/tasks", body)
+	if resp.StatusCode != 201 {
+		t.Fatalf("create %s: status %d: %s", body, resp.StatusCode, b)
+	}
+	return decode[Task](t, b)
+}
+
+// errorCode returns the code of an APIError response body.
+func errorCode(t *testing.T, b []byte) string {
+	t.Helper()
+	return decode[struct{ Error APIError }](t, b).Error.Code
+}
+
+func TestRouterCreateAndGet(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	created := createTask(t, srv, `{"title":"Write tests"}`)
+	if created.ID == "" || created.Version != 1 {
+		t.Fatalf("created task = %+v, want an id and version 1", created)
+	}
+
+	resp, b := request(t, srv, "GET", "/v1/tasks/"+created.ID, "")
+	if resp.StatusCode != 200 {
+		t.Fatalf("get: status %d: %s", resp.StatusCode, b)
+	}
+	if got := decode[Task](t, b); got.Title != "Write tests" {
+		t.Errorf("title = %q, want %q", got.Title, "Write tests")
+	}
+}
+
+func TestRouterUnknownTask(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	resp, b := request(t, srv, "GET", "/v1/tasks/missing", "")
+	if resp.StatusCode != 404 {
+		t.Fatalf("status = %d, want 404", resp.StatusCode)
+	}
+	if code := errorCode(t, b); code != CodeTaskNotFound {
+		t.Errorf("code = %q, want %q", code, CodeTaskNotFound)
+	}
+}
+
+// testToken signs a token for subject with the secret of a test server
+// configured with JWTSecret.
+func testToken(t *testing.T, secret, subject string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": subject}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
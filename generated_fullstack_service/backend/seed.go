@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// seedTasks returns a handful of sample tasks, due relative to now, that
+// cover the main task features.
+func seedTasks(now time.Time) []Task {
+	day := 24 * time.Hour
+	due := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	tasks := []Task{
+		{Title: "Try out the task API", Priority: PriorityHigh, Tags: []string{"getting-started"}},
+		{Title: "Buy groceries", DueDate: due(day), Tags: []string{"errands"}, Subtasks: []Subtask{
+			{Title: "Milk"}, {Title: "Bread", Done: true}, {Title: "Coffee"},
+		}},
+		{Title: "File expense report", Priority: PriorityHigh, DueDate: due(-2 * day), Tags: []string{"work"}},
+		{Title: "Water the plants", Recurrence: RecurrenceWeekly, DueDate: due(3 * day), Tags: []string{"home"}},
+		{Title: "Read a chapter", Priority: PriorityLow},
+		{Title: "Set up the dev environment", Done: true, Tags: []string{"getting-started", "work"}},
+	}
+	for i := range tasks {
+		t := &tasks[i]
+		applyDefaults(t)
+		t.ID = uuid.NewString()
+		t.CreatedAt, t.UpdatedAt = now, now
+		t.Version = 1
+	}
+	return tasks
+}
+
+// seedStore fills an empty store with seedTasks and reports how many tasks
+// it added. A store with any tasks, even trashed ones, is left alone.
+func seedStore(ctx context.Context, store TaskRepository) (int, error) {
+	existing, err := store.List(ctx)
+	if err != nil || len(existing) > 0 {
+		return 0, err
+	}
+	tasks := seedTasks(time.Now().UTC())
+	return len(tasks), store.CreateMany(ctx, tasks)
+}
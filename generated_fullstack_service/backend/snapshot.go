@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// snapshotVersion is the format of the documents ExportSnapshot writes.
+// RestoreSnapshot only loads documents of this version.
+const snapshotVersion = 1
+
+// Snapshot is a backup of the whole store: every task, trashed ones
+// included, exactly as stored, ids, timestamps and versions included.
+type Snapshot struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Tasks      []Task    `json:"tasks"`
+}
+
+// ExportSnapshot downloads every task as a Snapshot for RestoreSnapshot to
+// load back. Unlike the other exports it takes no filters.
+func (h *TaskHandler) ExportSnapshot(c *gin.Context) {
+	tasks, err := h.repo.List(c.Request.Context())
+	if err != nil {
+		respondError(c, 500, CodeInternal, err.Error())
+		return
+	}
+	now := time.Now().UTC()
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="tasks-%s.json"`, now.Format("20060102T150405Z")))
+	c.JSON(200, Snapshot{Version: snapshotVersion, ExportedAt: now, Tasks: tasks})
+}
+
+// RestoreSnapshot replaces every task in the store with those of the
+// uploaded Snapshot, in one atomic change. It needs ?confirm=true, since
+// anything not in the snapshot is lost. The whole snapshot is validated
+// first, and any invalid task, reported by index, leaves the store as is.
+// Like an import, the body is capped by IMPORT_MAX_BYTES rather than
+// MAX_BODY_BYTES.
+func (h *TaskHandler) RestoreSnapshot(c *gin.Context) {
+	confirm, err := parseBoolQuery(c, "confirm")
+	if err != nil {
+		respondError(c, 400, CodeInvalidRequest, err.Error())
+		return
+	}
+	if confirm == nil || !*confirm {
+		respondError(c, 400, CodeInvalidRequest, "restoring replaces every task in the store; pass ?confirm=true to go ahead")
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.importMaxBytes)
+	var snap Snapshot
+	if err := bindJSON(c, &snap); err != nil {
+		respondDecodeError(c, err)
+		return
+	}
+	if snap.Version != snapshotVersion {
+		respondError(c, 400, CodeValidationFailed, fmt.Sprintf("unsupported snapshot version %d: must be %d", snap.Version, snapshotVersion))
+		return
+	}
+	if snap.Tasks == nil {
+		respondError(c, 400, CodeValidationFailed, "tasks is required; send an empty list to clear the store")
+		return
+	}
+
+	var invalid []gin.H
+	ids := make(map[string]bool, len(snap.Tasks))
+	for i := range snap.Tasks {
+		if err := validateSnapshotTask(&snap.Tasks[i], ids); err != nil {
+			invalid = append(invalid, gin.H{"index": i, "error": err.Error()})
+		}
+	}
+	if len(invalid) > 0 {
+		respondErrorDetails(c, 400, CodeValidationFailed, "one or more tasks are invalid", invalid)
+		return
+	}
+
+	ctx := c.Request.Context()
+	removed, err := h.as(ctx, c.GetString(subjectKey)).ReplaceAll(ctx, snap.Tasks)
+	if err != nil {
+		respondModifyError(c, err)
+		return
+	}
+	tasksGauge.Set(float64(len(snap.Tasks)))
+	c.JSON(200, gin.H{"restored": len(snap.Tasks), "removed": len(removed)})
+}
+
+// validateSnapshotTask checks a task from a snapshot like any other body,
+// after filling in its defaults, and also the fields the server normally
+// sets: a unique id, which is added to ids, and a version.
+func validateSnapshotTask(t *Task, ids map[string]bool) error {
+	switch {
+	case t.ID == "":
+		return &ValidationError{Msg: "id is required"}
+	case len(t.ID) > maxTaskIDLength:
+		return &ValidationError{Msg: fmt.Sprintf("id must be at most %d characters", maxTaskIDLength)}
+	case ids[t.ID]:
+		return &ValidationError{Msg: fmt.Sprintf("duplicate id %q", t.ID)}
+	case t.Version < 1:
+		return &ValidationError{Msg: "version must be at least 1"}
+	}
+	ids[t.ID] = true
+	applyDefaults(t)
+	return validateTask(*t)
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// taskColumnNames lists the tasks table columns in the order scanTask reads
+// them and taskArgs writes them.
+var taskColumnNames = []string{"id", "title", "done", "created_at", "updated_at", "version", "priority", "due_date", "deleted_at", "tags", "subtasks", "recurrence", "parent_id", "owner", "sort_order", "comments", "completion_mode", "archived"}
+
+var (
+	taskColumns      = strings.Join(taskColumnNames, ", ")
+	taskPlaceholders = strings.TrimSuffix(strings.Repeat("?, ", len(taskColumnNames)), ", ")
+	taskAssignments  = strings.Join(taskColumnNames, " = ?, ") + " = ?"
+)
+
+// SQLiteStore is a TaskRepository that persists tasks in a SQLite database file.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite only supports a single writer; serialize access through one
+	// connection so concurrent requests don't trip over SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+
+	if err := runMigrations(context.Background(), sqliteMigrator{db}, "migrations/sqlite"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// sqliteMigrator runs migrations on a SQLiteStore's database. The store
+// opens a single connection, and a transaction that writes takes SQLite's
+// file lock, so two processes on the same file apply a migration only once.
+type sqliteMigrator struct {
+	db *sql.DB
+}
+
+func (m sqliteMigrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`)
+	return err
+}
+
+func (m sqliteMigrator) apply(ctx context.Context, mig migration) (bool, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	// Claim the version first: the insert takes the write lock, and a
+	// version another process has applied in the meantime is a conflict.
+	res, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?) ON CONFLICT (version) DO NOTHING`,
+		mig.version, mig.name, time.Now().UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, mig.sql); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(row rowScanner) (Task, error) {
+	var t Task
+	var createdAt, updatedAt string
+	var dueDate, deletedAt sql.NullString
+	var tags, subtasks, comments string
+	if err := row.Scan(&t.ID, &t.Title, &t.Done, &createdAt, &updatedAt, &t.Version, &t.Priority, &dueDate, &deletedAt, &tags, &subtasks, &t.Recurrence, &t.ParentID, &t.Owner, &t.Order, &comments, &t.CompletionMode, &t.Archived); err != nil {
+		return Task{}, err
+	}
+	if err := json.Unmarshal([]byte(tags), &t.Tags); err != nil {
+		return Task{}, fmt.Errorf("task %s: decode tags: %w", t.ID, err)
+	}
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	if err := json.Unmarshal([]byte(subtasks), &t.Subtasks); err != nil {
+		return Task{}, fmt.Errorf("task %s: decode subtasks: %w", t.ID, err)
+	}
+	if t.Subtasks == nil {
+		t.Subtasks = []Subtask{}
+	}
+	if err := json.Unmarshal([]byte(comments), &t.Comments); err != nil {
+		return Task{}, fmt.Errorf("task %s: decode comments: %w", t.ID, err)
+	}
+	if t.Comments == nil {
+		t.Comments = []Comment{}
+	}
+	t.CreatedAt = parseDBTime(createdAt)
+	t.UpdatedAt = parseDBTime(updatedAt)
+	t.DueDate = parseNullableDBTime(dueDate)
+	t.DeletedAt = parseNullableDBTime(deletedAt)
+	return t, nil
+}
+
+// taskArgs returns t's column values in taskColumns order.
+func taskArgs(t Task) []any {
+	return []any{t.ID, t.Title, t.Done, formatDBTime(t.CreatedAt), formatDBTime(t.UpdatedAt), t.Version, t.Priority, nullableDBTime(t.DueDate), nullableDBTime(t.DeletedAt), encodeTags(t.Tags), encodeSubtasks(t.Subtasks), t.Recurrence, t.ParentID, t.Owner, t.Order, encodeComments(t.Comments), t.CompletionMode, t.Archived}
+}
+
+// encodeTags stores tags as a JSON array; nil is stored as [].
+func encodeTags(tags []string) string {
+	if len(tags) == 0 {
+		return "[]"
+	}
+	b, _ := json.Marshal(tags)
+	return string(b)
+}
+
+// encodeSubtasks stores subtasks as a JSON array; nil is stored as [].
+func encodeSubtasks(subtasks []Subtask) string {
+	if len(subtasks) == 0 {
+		return "[]"
+	}
+	b, _ := json.Marshal(subtasks)
+	return string(b)
+}
+
+// encodeComments stores comments as a JSON array; nil is stored as [].
+func encodeComments(comments []Comment) string {
+	if len(comments) == 0 {
+		return "[]"
+	}
+	b, _ := json.Marshal(comments)
+	return string(b)
+}
+
+func formatDBTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// nullableDBTime stores a nil time as SQL NULL.
+func nullableDBTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatDBTime(*t)
+}
+
+func parseNullableDBTime(s sql.NullString) *time.Time {
+	if !s.Valid {
+		return nil
+	}
+	t := parseDBTime(s.String)
+	return &t
+}
+
+// parseDBTime parses a stored timestamp. Rows written before the column
+// existed hold an empty string and come back as the zero time.
+func parseDBTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Ping runs a trivial query, which unlike db.Ping also catches a database
+// file that has become unreadable.
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	var one int
+	return s.db.QueryRowContext(ctx, `SELECT 1`).Scan(&one)
+}
+
+// List returns all tasks in insertion order.
+func (s *SQLiteStore) List(ctx context.Context) ([]Task, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+taskColumns+` FROM tasks ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []Task{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// Get returns the task with the given id and reports whether it was found.
+func (s *SQLiteStore) Get(ctx context.Context, id string) (Task, bool, error) {
+	return getSQLiteTask(ctx, s.db, id)
+}
+
+func (s *SQLiteStore) Create(ctx context.Context, t Task) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO tasks (`+taskColumns+`) VALUES (`+taskPlaceholders+`)`, taskArgs(t)...)
+	return sqliteInsertError(err)
+}
+
+// sqliteUnavailable reports whether err means the database file couldn't be
+// opened or was locked by another process, which may pass, rather than that
+// it was opened and a statement failed.
+func sqliteUnavailable(err error) bool {
+	var serr *sqlite.Error
+	if !errors.As(err, &serr) {
+		return false
+	}
+	switch serr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED, sqlite3.SQLITE_CANTOPEN:
+		return true
+	}
+	return false
+}
+
+// sqliteInsertError reports a primary key violation as errDuplicateID.
+func sqliteInsertError(err error) error {
+	var serr *sqlite.Error
+	if errors.As(err, &serr) && serr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+		return errDuplicateID
+	}
+	return err
+}
+
+// CreateMany inserts all of ts in a single transaction; either every task is
+// stored or none is.
+func (s *SQLiteStore) CreateMany(ctx context.Context, ts []Task) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO tasks (`+taskColumns+`) VALUES (`+taskPlaceholders+`)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, t := range ts {
+		if _, err := stmt.ExecContext(ctx, taskArgs(t)...); err != nil {
+			return sqliteInsertError(err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Update replaces the task with the given id and reports whether it was found.
+func (s *SQLiteStore) Update(ctx context.Context, id string, t Task) (bool, error) {
+	return updateSQLiteTask(ctx, s.db, id, t)
+}
+
+// Modify atomically applies fn to the task with the given id. If fn returns an
+// error the task is left unchanged and that error is returned as is.
+func (s *SQLiteStore) Modify(ctx context.Context, id string, fn func(*Task) error) (Task, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Task{}, false, err
+	}
+	defer tx.Rollback()
+
+	t, found, err := getSQLiteTask(ctx, tx, id)
+	if err != nil || !found {
+		return Task{}, found, err
+	}
+	if err := fn(&t); err != nil {
+		return Task{}, true, err
+	}
+	if _, err := updateSQLiteTask(ctx, tx, id, t); err != nil {
+		return Task{}, true, err
+	}
+	return t, true, tx.Commit()
+}
+
+// ModifyMany applies fn to each task within a single transaction.
+func (s *SQLiteStore) ModifyMany(ctx context.Context, ids []string, fn func(*Task) error) ([]Task, []string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	modified, missing := []Task{}, []string{}
+	for _, id := range ids {
+		t, found, err := getSQLiteTask(ctx, tx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !found {
+			missing = append(missing, id)
+			continue
+		}
+		err = fn(&t)
+		if errors.Is(err, errSkip) {
+			missing = append(missing, id)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := updateSQLiteTask(ctx, tx, id, t); err != nil {
+			return nil, nil, err
+		}
+		modified = append(modified, t)
+	}
+	return modified, missing, tx.Commit()
+}
+
+// Delete removes the task with the given id and reports whether it was found.
+func (s *SQLiteStore) Delete(ctx context.Context, id string) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM tasks WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// TrashCompleted moves every done, active task to the trash and returns the
+// tasks as they are after the move.
+func (s *SQLiteStore) TrashCompleted(ctx context.Context, at time.Time) ([]Task, error) {
+	ts := formatDBTime(at)
+	rows, err := s.db.QueryContext(ctx, `UPDATE tasks SET deleted_at = ?, updated_at = ?, version = version + 1
+		WHERE done = 1 AND deleted_at IS NULL RETURNING `+taskColumns, ts, ts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trashed := []Task{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		trashed = append(trashed, t)
+	}
+	return trashed, rows.Err()
+}
+
+// ReplaceAll deletes every task and inserts ts in a single transaction, so
+// their rowids, and with them the List order, follow ts.
+func (s *SQLiteStore) ReplaceAll(ctx context.Context, ts []Task) ([]Task, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `DELETE FROM tasks RETURNING `+taskColumns)
+	if err != nil {
+		return nil, err
+	}
+	removed := []Task{}
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		removed = append(removed, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO tasks (`+taskColumns+`) VALUES (`+taskPlaceholders+`)`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	for _, t := range ts {
+		if _, err := stmt.ExecContext(ctx, taskArgs(t)...); err != nil {
+			return nil, sqliteInsertError(err)
+		}
+	}
+	return removed, tx.Commit()
+}
+
+// Stats streams the active tasks through TaskStats.add rather than building
+// the full list in memory.
+func (s *SQLiteStore) Stats(ctx context.Context, now time.Time) (TaskStats, error) {
+	stats := newTaskStats()
+	rows, err := s.db.QueryContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE deleted_at IS NULL`)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return stats, err
+		}
+		stats.add(t, now)
+	}
+	return stats, rows.Err()
+}
+
+func (s *SQLiteStore) AppendAudit(e AuditEntry) error {
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO audit_log (at, subject, action, task_id, changes, undoes) VALUES (?, ?, ?, ?, ?, ?)`,
+		formatDBTime(e.At), e.Subject, e.Action, e.TaskID, string(changes), e.Undoes)
+	return err
+}
+
+// auditEntryColumns are the audit_log columns read by scanAuditEntry.
+const auditEntryColumns = `id, at, subject, action, task_id, changes, undoes`
+
+func scanAuditEntry(row rowScanner) (AuditEntry, error) {
+	var e AuditEntry
+	var at, changes string
+	if err := row.Scan(&e.ID, &at, &e.Subject, &e.Action, &e.TaskID, &changes, &e.Undoes); err != nil {
+		return e, err
+	}
+	e.At = parseDBTime(at)
+	return e, json.Unmarshal([]byte(changes), &e.Changes)
+}
+
+func (s *SQLiteStore) LastUndoable(subject string) (AuditEntry, bool, error) {
+	e, err := scanAuditEntry(s.db.QueryRow(`SELECT `+auditEntryColumns+` FROM audit_log a
+		WHERE subject = ? AND undoes = 0 AND NOT EXISTS (SELECT 1 FROM audit_log u WHERE u.undoes = a.id)
+		ORDER BY id DESC LIMIT 1`, subject))
+	if errors.Is(err, sql.ErrNoRows) {
+		return AuditEntry{}, false, nil
+	}
+	if err != nil {
+		return AuditEntry{}, false, err
+	}
+	return e, true, nil
+}
+
+func (s *SQLiteStore) ListAudit(taskID string, limit, offset int) ([]AuditEntry, int, error) {
+	where, args := "", []any{}
+	if taskID != "" {
+		where, args = " WHERE task_id = ?", append(args, taskID)
+	}
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.db.Query(`SELECT `+auditEntryColumns+` FROM audit_log`+where+` ORDER BY id LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	entries := []AuditEntry{}
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
+
+// execer and queryer are satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// getSQLiteTask is the single-row lookup shared by Get and Modify.
+func getSQLiteTask(ctx context.Context, db queryer, id string) (Task, bool, error) {
+	t, err := scanTask(db.QueryRowContext(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, false, nil
+	}
+	if err != nil {
+		return Task{}, false, err
+	}
+	return t, true, nil
+}
+
+func updateSQLiteTask(ctx context.Context, db execer, id string, t Task) (bool, error) {
+	args := append(taskArgs(t), id)
+	res, err := db.ExecContext(ctx, `UPDATE tasks SET `+taskAssignments+` WHERE id = ?`, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
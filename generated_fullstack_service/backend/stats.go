@@ -0,0 +1,43 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TaskStats summarises the active (non-trashed) tasks.
+type TaskStats struct {
+	Total      int            `json:"total"`
+	Done       int            `json:"done"`
+	Pending    int            `json:"pending"`
+	Overdue    int            `json:"overdue"`
+	ByPriority map[string]int `json:"by_priority"`
+}
+
+func newTaskStats() TaskStats {
+	return TaskStats{ByPriority: map[string]int{PriorityLow: 0, PriorityMedium: 0, PriorityHigh: 0}}
+}
+
+// add counts t, which must not be in the trash.
+func (s *TaskStats) add(t Task, now time.Time) {
+	s.Total++
+	if t.Done {
+		s.Done++
+	} else {
+		s.Pending++
+	}
+	if isOverdue(t, now) {
+		s.Overdue++
+	}
+	s.ByPriority[t.Priority]++
+}
+
+func (h *TaskHandler) Stats(c *gin.Context) {
+	stats, err := h.repo.Stats(c.Request.Context(), time.Now())
+	if err != nil {
+		respondError(c, 500, CodeInternal, err.Error())
+		return
+	}
+	c.JSON(200, stats)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestTaskStoreRejectsDuplicateIDs(t *testing.T) {
+	ctx := context.Background()
+	s := NewTaskStore()
+	if err := s.Create(ctx, Task{ID: "a", Title: "First"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Create(ctx, Task{ID: "a", Title: "Second"}); !errors.Is(err, errDuplicateID) {
+		t.Errorf("second create with the same id: %v, want errDuplicateID", err)
+	}
+	if err := s.CreateMany(ctx, []Task{{ID: "b"}, {ID: "b"}}); !errors.Is(err, errDuplicateID) {
+		t.Errorf("batch with a repeated id: %v, want errDuplicateID", err)
+	}
+	tasks, _ := s.List(ctx)
+	if len(tasks) != 1 || tasks[0].Title != "First" {
+		t.Errorf("store holds %+v, want only the first task", tasks)
+	}
+}
+
+// racingStore hides existing tasks from Modify and Get, as if another
+// request had created the task just after this one looked for it.
+type racingStore struct {
+	*TaskStore
+}
+
+func (s racingStore) Modify(context.Context, string, func(*Task) error) (Task, bool, error) {
+	return Task{}, false, nil
+}
+
+func (s racingStore) Get(context.Context, string) (Task, bool, error) {
+	return Task{}, false, nil
+}
+
+func TestUpsertOfExistingIDConflicts(t *testing.T) {
+	store := NewTaskStore()
+	if err := store.Create(context.Background(), Task{ID: "taken", Title: "Already here"}); err != nil {
+		t.Fatal(err)
+	}
+	r, _ := NewRouter(racingStore{store}, testConfig())
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	resp, b := request(t, srv, "PUT", "/v1/tasks/taken", `{"title":"Duplicate"}`)
+	if resp.StatusCode != 409 {
+		t.Fatalf("status = %d, want 409: %s", resp.StatusCode, b)
+	}
+	if code := errorCode(t, b); code != CodeDuplicateID {
+		t.Errorf("code = %q, want %q", code, CodeDuplicateID)
+	}
+}
+
+// scanForTask is the lookup TaskStore used before it kept an index: a walk
+// over its slice of tasks.
+func scanForTask(tasks []Task, id string) (Task, bool) {
+	for i := range tasks {
+		if tasks[i].ID == id {
+			return tasks[i], true
+		}
+	}
+	return Task{}, false
+}
+
+// BenchmarkTaskStoreLookup compares the old linear scan with the indexed
+// Get, looking up the last task, which is the scan's worst case.
+func BenchmarkTaskStoreLookup(b *testing.B) {
+	ctx := context.Background()
+	for _, n := range []int{10, 1000, 100000} {
+		tasks := make([]Task, n)
+		for i := range tasks {
+			tasks[i] = Task{ID: strconv.Itoa(i), Title: "Task " + strconv.Itoa(i)}
+		}
+		s := NewTaskStore()
+		if err := s.CreateMany(ctx, tasks); err != nil {
+			b.Fatal(err)
+		}
+		last := tasks[n-1].ID
+
+		b.Run(fmt.Sprintf("scan/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, ok := scanForTask(tasks, last); !ok {
+					b.Fatal("not found")
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("index/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, ok, _ := s.Get(ctx, last); !ok {
+					b.Fatal("not found")
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkTaskStoreMixed runs a mix of nine Gets to one Modify across
+// GOMAXPROCS times p goroutines, which is the load the single lock in
+// TaskStore is meant to stand up to.
+func BenchmarkTaskStoreMixed(b *testing.B) {
+	ctx := context.Background()
+	const n = 1000
+	tasks := make([]Task, n)
+	for i := range tasks {
+		tasks[i] = Task{ID: strconv.Itoa(i), Title: "Task " + strconv.Itoa(i)}
+	}
+	for _, p := range []int{1, 4, 16, 64} {
+		s := NewTaskStore()
+		if err := s.CreateMany(ctx, tasks); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("p=%d", p), func(b *testing.B) {
+			b.SetParallelism(p)
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					id := tasks[i%n].ID
+					if i%10 == 0 {
+						s.Modify(ctx, id, func(t *Task) error {
+							t.Version++
+							return nil
+						})
+					} else {
+						s.Get(ctx, id)
+					}
+				}
+			})
+		})
+	}
+}
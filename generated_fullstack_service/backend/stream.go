@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamHeartbeat is how often an idle stream sends a comment line, which
+// keeps proxies from timing the connection out.
+const streamHeartbeat = 15 * time.Second
+
+// Stream serves task change events as Server-Sent Events until the client
+// disconnects. Each message's event name is the change type and its data is
+// the TaskEvent as JSON, or a CloudEvent in that format.
+func (h *TaskHandler) Stream(c *gin.Context) {
+	events, unsubscribe := h.events.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(200)
+	// Send an initial comment so clients see the stream open immediately.
+	fmt.Fprint(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				// Dropped for falling behind, or shutting down; either way
+				// the client will reconnect.
+				return
+			}
+			data, err := json.Marshal(formatEvent(ev, h.eventFormat))
+			if err != nil {
+				c.Error(err)
+				return
+			}
+			fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", ev.Type, data)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errSubtaskNotFound = errors.New("subtask not found")
+
+// CreateSubtask appends a subtask to the end of a task's checklist.
+func (h *TaskHandler) CreateSubtask(c *gin.Context) {
+	var sub Subtask
+	if err := bindSubtask(c, &sub); err != nil {
+		respondDecodeError(c, err)
+		return
+	}
+	sub.ID = uuid.NewString()
+	_, found, err := h.modifySubtasks(c, func(subs []Subtask) ([]Subtask, error) {
+		return append(subs, sub), nil
+	})
+	if !respondSubtaskError(c, found, err) {
+		return
+	}
+	c.Header("Location", c.Request.URL.Path+"/"+sub.ID)
+	c.JSON(201, sub)
+}
+
+// UpdateSubtask replaces a subtask's title and done flag.
+func (h *TaskHandler) UpdateSubtask(c *gin.Context) {
+	var sub Subtask
+	if err := bindSubtask(c, &sub); err != nil {
+		respondDecodeError(c, err)
+		return
+	}
+	sub.ID = c.Param("subId")
+	_, found, err := h.modifySubtasks(c, func(subs []Subtask) ([]Subtask, error) {
+		i := subtaskIndex(subs, sub.ID)
+		if i < 0 {
+			return nil, errSubtaskNotFound
+		}
+		subs[i] = sub
+		return subs, nil
+	})
+	if !respondSubtaskError(c, found, err) {
+		return
+	}
+	c.JSON(200, sub)
+}
+
+func (h *TaskHandler) DeleteSubtask(c *gin.Context) {
+	id := c.Param("subId")
+	_, found, err := h.modifySubtasks(c, func(subs []Subtask) ([]Subtask, error) {
+		i := subtaskIndex(subs, id)
+		if i < 0 {
+			return nil, errSubtaskNotFound
+		}
+		return append(subs[:i], subs[i+1:]...), nil
+	})
+	if !respondSubtaskError(c, found, err) {
+		return
+	}
+	c.Status(204)
+}
+
+// bindSubtask decodes and validates a subtask body. Any id in it is ignored.
+func bindSubtask(c *gin.Context, sub *Subtask) error {
+	if err := bindJSON(c, sub); err != nil {
+		return err
+	}
+	return validateSubtask(*sub)
+}
+
+// modifySubtasks applies fn to the checklist of the active task named by the
+// :id parameter, bumping the task's version and, in auto completion mode,
+// updating its done flag to match. fn is given a copy it may
+// modify in place, so the stored slice is never aliased.
+func (h *TaskHandler) modifySubtasks(c *gin.Context, fn func([]Subtask) ([]Subtask, error)) (Task, bool, error) {
+	return modifyActive(c.Request.Context(), h.asCaller(c), c.Param("id"), func(t *Task) error {
+		subs, err := fn(append([]Subtask{}, t.Subtasks...))
+		if err != nil {
+			return err
+		}
+		t.Subtasks = subs
+		settleCompletion(t, t.Done)
+		t.UpdatedAt = time.Now().UTC()
+		t.Version++
+		return nil
+	})
+}
+
+// respondSubtaskError reports a failed modifySubtasks call and returns
+// whether the request may go on to send its success response.
+func respondSubtaskError(c *gin.Context, found bool, err error) bool {
+	switch {
+	case errors.Is(err, errSubtaskNotFound):
+		respondError(c, 404, CodeSubtaskNotFound, err.Error())
+	case err != nil:
+		respondModifyError(c, err)
+	case !found:
+		respondError(c, 404, CodeTaskNotFound, "task not found")
+	default:
+		return true
+	}
+	return false
+}
+
+func subtaskIndex(subs []Subtask, id string) int {
+	for i := range subs {
+		if subs[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"task-service/client"
+)
+
+const (
+	maxTitleLength   = 280
+	maxCommentLength = 2000
+)
+
+// Recurrence intervals. When a recurring task is completed, the next
+// occurrence is created automatically.
+const (
+	RecurrenceNone    = "none"
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+)
+
+var recurrences = []string{RecurrenceNone, RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly}
+
+// Task priorities, ordered by priorityRank.
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+)
+
+var priorityRank = map[string]int{
+	PriorityLow:    0,
+	PriorityMedium: 1,
+	PriorityHigh:   2,
+}
+
+// Task, Subtask and Comment are defined by the client package, so the
+// server and its Go client share one definition of the wire format.
+type (
+	Task    = client.Task
+	Subtask = client.Subtask
+	Comment = client.Comment
+)
+
+// TaskPatch is a partial update. Nil fields are left untouched, which lets
+// clients tell "omitted" apart from "set to the zero value".
+type TaskPatch struct {
+	Title    *string `json:"title"`
+	Done     *bool   `json:"done"`
+	Priority *string `json:"priority"`
+	// DueDate may be set to null to clear the due date.
+	DueDate optionalTime `json:"due_date"`
+	// Tags, when present, replaces the whole tag list.
+	Tags           *[]string `json:"tags"`
+	Recurrence     *string   `json:"recurrence"`
+	CompletionMode *string   `json:"completion_mode"`
+	Archived       *bool     `json:"archived"`
+	// Owner is ignored when auth is enabled.
+	Owner *string `json:"owner"`
+	// Version, when set, must match the stored version for the patch to apply.
+	Version *int `json:"version"`
+}
+
+func (p TaskPatch) apply(t *Task) {
+	if p.Title != nil {
+		t.Title = normalizeTitle(*p.Title)
+	}
+	if p.Done != nil {
+		t.Done = *p.Done
+	}
+	if p.Priority != nil {
+		t.Priority = *p.Priority
+	}
+	if p.DueDate.Set {
+		t.DueDate = p.DueDate.Value
+	}
+	if p.Recurrence != nil {
+		t.Recurrence = *p.Recurrence
+	}
+	if p.CompletionMode != nil {
+		t.CompletionMode = *p.CompletionMode
+	}
+	if p.Archived != nil {
+		t.Archived = *p.Archived
+	}
+	if p.Owner != nil {
+		t.Owner = *p.Owner
+	}
+	if p.Tags != nil {
+		t.Tags = normalizeTags(*p.Tags)
+		if t.Tags == nil {
+			t.Tags = []string{}
+		}
+	}
+}
+
+// optionalTime is a JSON timestamp that records whether it was present in the
+// input at all, so that an explicit null can be told apart from an omission.
+type optionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (o *optionalTime) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+	var t time.Time
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	o.Value = &t
+	return nil
+}
+
+// isOverdue reports whether t is not done and was due before now.
+func isOverdue(t Task, now time.Time) bool {
+	return !t.Done && t.DueDate != nil && t.DueDate.Before(now)
+}
+
+// applyDefaults normalizes the title and tags of a full task body and fills
+// in fields a client may leave out of it. An omitted done is already false.
+func applyDefaults(t *Task) {
+	t.Title = normalizeTitle(t.Title)
+	t.Tags = normalizeTags(t.Tags)
+	if t.Priority == "" {
+		t.Priority = PriorityMedium
+	}
+	if t.Recurrence == "" {
+		t.Recurrence = RecurrenceNone
+	}
+	if t.CompletionMode == "" {
+		t.CompletionMode = CompletionManual
+	}
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	if t.Subtasks == nil {
+		t.Subtasks = []Subtask{}
+	}
+	if t.Comments == nil {
+		t.Comments = []Comment{}
+	}
+	for i := range t.Subtasks {
+		if t.Subtasks[i].ID == "" {
+			t.Subtasks[i].ID = uuid.NewString()
+		}
+	}
+}
+
+// checkVersion returns a *VersionConflictError if the client supplied a
+// version that differs from the stored one. A zero version skips the check.
+func checkVersion(stored Task, clientVersion int) error {
+	if clientVersion != 0 && clientVersion != stored.Version {
+		return &VersionConflictError{Current: stored.Version}
+	}
+	return nil
+}
+
+// VersionConflictError reports a write based on an outdated task version.
+type VersionConflictError struct {
+	Current int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict: current version is %d", e.Current)
+}
+
+// ValidationError reports a task that breaks one of the field rules. Fields,
+// when set, lists every field that does, and Msg sums them up.
+type ValidationError struct {
+	Msg    string
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return e.Msg
+}
+
+// FieldError is one field's problem within a ValidationError.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// fieldErrors collects the problems found in a body instead of stopping at
+// the first, so a client can fix every field in one round trip.
+type fieldErrors []FieldError
+
+func (errs *fieldErrors) add(field, msg string) {
+	*errs = append(*errs, FieldError{Field: field, Message: msg})
+}
+
+// err returns nil if nothing was added, or a *ValidationError listing it all.
+func (errs fieldErrors) err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Message
+	}
+	return &ValidationError{Msg: strings.Join(msgs, "; "), Fields: errs}
+}
+
+// validateTask checks the client-controlled fields of a task. It enforces the
+// same rules as the binding tags plus the ones tags can't express, such as a
+// whitespace-only title. Every broken field is reported, each with its first
+// problem.
+func validateTask(t Task) error {
+	var errs fieldErrors
+	if strings.TrimSpace(t.Title) == "" {
+		errs.add("title", "title is required")
+	} else if utf8.RuneCountInString(t.Title) > maxTitleLength {
+		errs.add("title", fmt.Sprintf("title must be at most %d characters", maxTitleLength))
+	}
+	if _, ok := priorityRank[t.Priority]; !ok {
+		errs.add("priority", "priority must be one of low, medium, high")
+	}
+	if !isRecurrence(t.Recurrence) {
+		errs.add("recurrence", "recurrence must be one of "+strings.Join(recurrences, ", "))
+	}
+	if !isCompletionMode(t.CompletionMode) {
+		errs.add("completion_mode", "completion_mode must be one of "+strings.Join(completionModes, ", "))
+	}
+	if msg := tagsProblem(t.Tags); msg != "" {
+		errs.add("tags", msg)
+	}
+	if msg := subtasksProblem(t.Subtasks); msg != "" {
+		errs.add("subtasks", msg)
+	}
+	return errs.err()
+}
+
+// tagsProblem describes the first invalid tag, or returns "".
+func tagsProblem(tags []string) string {
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			return "tags must not be empty"
+		}
+		if seen[tag] {
+			return fmt.Sprintf("duplicate tag %q", tag)
+		}
+		seen[tag] = true
+	}
+	return ""
+}
+
+// subtasksProblem describes the first invalid subtask, or returns "".
+func subtasksProblem(subs []Subtask) string {
+	ids := make(map[string]bool, len(subs))
+	for _, s := range subs {
+		if err := validateSubtask(s); err != nil {
+			return err.Error()
+		}
+		if ids[s.ID] {
+			return fmt.Sprintf("duplicate subtask id %q", s.ID)
+		}
+		ids[s.ID] = true
+	}
+	return ""
+}
+
+func validateSubtask(s Subtask) error {
+	if strings.TrimSpace(s.Title) == "" {
+		return &ValidationError{Msg: "subtask title is required"}
+	}
+	if utf8.RuneCountInString(s.Title) > maxTitleLength {
+		return &ValidationError{Msg: fmt.Sprintf("subtask title must be at most %d characters", maxTitleLength)}
+	}
+	return nil
+}
+
+func validateComment(cm Comment) error {
+	var errs fieldErrors
+	if strings.TrimSpace(cm.Body) == "" {
+		errs.add("body", "comment body is required")
+	} else if utf8.RuneCountInString(cm.Body) > maxCommentLength {
+		errs.add("body", fmt.Sprintf("comment body must be at most %d characters", maxCommentLength))
+	}
+	return errs.err()
+}
+
+// sameTitle reports whether two titles are the same ignoring case, leading
+// and trailing whitespace, and how runs of whitespace inside them are
+// spaced.
+func sameTitle(a, b string) bool {
+	return strings.EqualFold(normalizeTitle(a), normalizeTitle(b))
+}
+
+// normalizeTitle trims title and collapses every run of whitespace inside it
+// to a single space, so " Buy  milk" is stored as "Buy milk". Case is kept.
+func normalizeTitle(title string) string {
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// normalizeTags returns a copy of tags with the surrounding whitespace
+// trimmed from each. A nil slice stays nil.
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	out := make([]string, len(tags))
+	for i, tag := range tags {
+		out[i] = strings.TrimSpace(tag)
+	}
+	return out
+}
+
+// hasTag reports whether t is labelled with tag.
+func hasTag(t Task, tag string) bool {
+	for _, tt := range t.Tags {
+		if tt == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// bindTask decodes the JSON request body into t, fills in defaults and
+// validates the result. The binding tags are enforced by validateTask, so
+// clients always get the same message for the same mistake.
+func bindTask(c *gin.Context, t *Task) error {
+	if err := bindJSON(c, t); err != nil {
+		return err
+	}
+	applyDefaults(t)
+	return validateTask(*t)
+}
+
+// bindJSON decodes the JSON request body into v. A body sent with another
+// content type, an empty body, malformed JSON and a key v has no field for,
+// which is most likely a typo, are all reported with a message saying which
+// it was; a request without a Content-Type is assumed to be JSON.
+func bindJSON(c *gin.Context, v any) error {
+	if ct := c.ContentType(); ct != "" && !isJSONContentType(ct) {
+		return fmt.Errorf("Content-Type must be application/json, not %s", ct)
+	}
+	if c.Request.Body == nil {
+		return errEmptyBody
+	}
+	return decodeJSON(c.Request.Body, v)
+}
+
+// decodeJSON decodes r into v as bindJSON does, for JSON that doesn't come
+// straight from a request body.
+func decodeJSON(r io.Reader, v any) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return errEmptyBody
+		}
+		return describeBindError(err)
+	}
+	return nil
+}
+
+var errEmptyBody = errors.New("request body is empty")
+
+// describeBindError turns decoding errors with an unhelpful message into
+// ones naming the problem. Bad due dates become a *ValidationError, as they
+// are a rule violation rather than malformed JSON.
+func describeBindError(err error) error {
+	var perr *time.ParseError
+	var serr *json.SyntaxError
+	var terr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &perr):
+		return &ValidationError{Msg: "due_date must be an RFC3339 timestamp"}
+	case errors.As(err, &serr):
+		return fmt.Errorf("malformed JSON at offset %d: %v", serr.Offset, serr)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("malformed JSON: unexpected end of body")
+	case errors.As(err, &terr) && terr.Field != "":
+		return fmt.Errorf("%s has the wrong type: got a JSON %s", terr.Field, terr.Value)
+	case errors.As(err, &terr):
+		return fmt.Errorf("body has the wrong type: got a JSON %s", terr.Value)
+	}
+	// encoding/json has no error type for these.
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return fmt.Errorf("unknown field %s", field)
+	}
+	return err
+}
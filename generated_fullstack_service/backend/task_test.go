@@ -0,0 +1,80 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSameTitle(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{"Buy milk", "Buy milk", true},
+		{"Buy milk", "buy MILK", true},
+		{"Buy milk", "  Buy milk  ", true},
+		{"Buy milk", "Buy   milk", true},
+		{"Buy milk", "\tbuy\n milk ", true},
+		{"ÉCOLE", "école", true},
+		{"Buy milk", "Buy milks", false},
+		{"Buy milk", "Buymilk", false},
+		{"Buy milk", "", false},
+	}
+	for _, tc := range cases {
+		if got := sameTitle(tc.a, tc.b); got != tc.want {
+			t.Errorf("sameTitle(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+		if got := sameTitle(tc.b, tc.a); got != tc.want {
+			t.Errorf("sameTitle(%q, %q) = %v, want %v", tc.b, tc.a, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"Buy milk", "Buy milk"},
+		{"  Buy milk  ", "Buy milk"},
+		{"Buy   milk", "Buy milk"},
+		{"\tBuy\n\r milk\u00a0", "Buy milk"},
+		{"Buy MILK", "Buy MILK"},
+		{"   ", ""},
+		{"", ""},
+	}
+	for _, tc := range cases {
+		if got := normalizeTitle(tc.in); got != tc.want {
+			t.Errorf("normalizeTitle(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	if got := normalizeTags(nil); got != nil {
+		t.Errorf("normalizeTags(nil) = %#v, want nil", got)
+	}
+	if got := normalizeTags([]string{}); got == nil || len(got) != 0 {
+		t.Errorf("normalizeTags([]) = %#v, want an empty slice", got)
+	}
+	in := []string{" work ", "home", "\tlong  tag\n", ""}
+	got := normalizeTags(in)
+	if want := []string{"work", "home", "long  tag", ""}; !slices.Equal(got, want) {
+		t.Errorf("normalizeTags(%q) = %q, want %q", in, got, want)
+	}
+	if in[0] != " work " {
+		t.Errorf("normalizeTags changed its argument to %q", in)
+	}
+}
+
+func TestNormalizeOnWrite(t *testing.T) {
+	srv := newTestServer(t, testConfig())
+	task := createTask(t, srv, `{"title":"  Buy   milk ","tags":[" shop "]}`)
+	if task.Title != "Buy milk" || !slices.Equal(task.Tags, []string{"shop"}) {
+		t.Errorf("created %q %q, want normalized title and tags", task.Title, task.Tags)
+	}
+	resp, b := request(t, srv, "PATCH", "/v1/tasks/"+task.ID, `{"title":" Buy  bread"}`)
+	if got := decode[Task](t, b); resp.StatusCode != 200 || got.Title != "Buy bread" {
+		t.Errorf("patch: status %d, title %q; want 200 and \"Buy bread\"", resp.StatusCode, got.Title)
+	}
+	if resp, _ := request(t, srv, "POST", "/v1/tasks", `{"title":"   "}`); resp.StatusCode != 422 {
+		t.Errorf("whitespace-only title: status %d, want 422", resp.StatusCode)
+	}
+}
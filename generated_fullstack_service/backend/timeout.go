@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultRequestTimeout bounds how long a handler may take to respond.
+const defaultRequestTimeout = 30 * time.Second
+
+// Timeout gives every request a deadline of d on its context and answers 503
+// if the handler hasn't finished by then. Responses are buffered until the
+// handler returns, so the excluded paths, which stream or hijack their
+// connection, bypass it entirely, as do NDJSON exports.
+//
+// It wraps the whole router rather than running as gin middleware because
+// the handler has to be left running in its own goroutine on timeout, and a
+// gin.Context must not outlive the middleware chain.
+func Timeout(d time.Duration, excludedPaths ...string) func(http.Handler) http.Handler {
+	excluded := make(map[string]bool, len(excludedPaths))
+	for _, p := range excludedPaths {
+		excluded[p] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if excluded[r.URL.Path] || isNDJSONExport(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+			// The request id is settled before the handler starts, so a
+			// timeout can report it without touching the response headers
+			// the handler may still be writing.
+			requestID := r.Header.Get(requestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = uuid.NewString()
+				r.Header = r.Header.Clone()
+				r.Header.Set(requestIDHeader, requestID)
+			}
+
+			tw := &timeoutWriter{h: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				for k, v := range tw.h {
+					w.Header()[k] = v
+				}
+				if tw.code == 0 {
+					tw.code = 200
+				}
+				w.WriteHeader(tw.code)
+				w.Write(tw.buf.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				// A cancelled context means the client has gone away and
+				// there is nobody to answer.
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					w.Header().Set("Content-Type", "application/json; charset=utf-8")
+					w.Header().Set(requestIDHeader, requestID)
+					w.WriteHeader(503)
+					json.NewEncoder(w).Encode(map[string]APIError{"error": {
+						Code:      CodeTimeout,
+						Message:   "request timed out",
+						RequestID: requestID,
+					}})
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers a response until the handler finishes, discarding it
+// if the request has already timed out.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	h        http.Header
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+// Header returns the buffered headers. Only the handler's goroutine uses
+// them until it has returned; a timeout never reads them.
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = 200
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
+
+// Flush is a no-op: nothing reaches the client before the handler returns.
+func (tw *timeoutWriter) Flush() {}
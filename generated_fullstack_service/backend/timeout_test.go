@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// slowHandler keeps writing response headers for a while after its request
+// has timed out, as a handler that ignores its deadline would.
+func slowHandler(gotID chan<- string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID <- r.Header.Get(requestIDHeader)
+		for stop := time.Now().Add(50 * time.Millisecond); time.Now().Before(stop); {
+			w.Header().Set("X-Progress", time.Now().String())
+		}
+	})
+}
+
+func TestTimeoutReportsRequestID(t *testing.T) {
+	for _, sent := range []string{"", "client-id"} {
+		gotID := make(chan string, 1)
+		srv := httptest.NewServer(Timeout(10 * time.Millisecond)(slowHandler(gotID)))
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		if sent != "" {
+			req.Header.Set(requestIDHeader, sent)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct{ Error APIError }
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		srv.Close()
+
+		if resp.StatusCode != 503 || body.Error.Code != CodeTimeout {
+			t.Fatalf("sent %q: status %d, code %q; want 503 %s", sent, resp.StatusCode, body.Error.Code, CodeTimeout)
+		}
+		handlerID := <-gotID
+		if sent != "" && handlerID != sent {
+			t.Errorf("handler saw request id %q, want %q", handlerID, sent)
+		}
+		if handlerID == "" || body.Error.RequestID != handlerID || resp.Header.Get(requestIDHeader) != handlerID {
+			t.Errorf("sent %q: handler id %q, body id %q, header id %q; want all the same",
+				sent, handlerID, body.Error.RequestID, resp.Header.Get(requestIDHeader))
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer is resolved through the global provider, so spans are no-ops until
+// setupTracing installs an exporting one.
+var tracer = otel.Tracer("task-service")
+
+// setupTracing exports spans over OTLP/HTTP to endpoint, a URL such as
+// http://collector:4318. With no endpoint nothing is exported, but incoming
+// trace context is still passed on. The returned function flushes pending
+// spans.
+func setupTracing(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "task-service"),
+			attribute.String("service.version", version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracing starts a server span per request, continuing the trace of an
+// incoming traceparent header, and makes it the parent of the store spans
+// started by handlers. It must run after RequestID.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		name := c.Request.Method + " " + route
+		if route == "" {
+			name = c.Request.Method
+		}
+		ctx, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", c.Request.URL.Path),
+				attribute.String("request_id", c.GetString(requestIDKey)),
+			))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, strconv.Itoa(status))
+		}
+	}
+}
+
+// tracingStore is a TaskRepository decorator that records a span around every
+// store call, as a child of the span in the call's context. The store is
+// handed the child's context so its own spans nest under it.
+type tracingStore struct {
+	TaskRepository
+}
+
+func (s *tracingStore) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "store."+op, trace.WithAttributes(attrs...))
+}
+
+// end records err, if any, on span and ends it.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func taskIDAttr(id string) attribute.KeyValue {
+	return attribute.String("task.id", id)
+}
+
+func (s *tracingStore) List(ctx context.Context) ([]Task, error) {
+	ctx, span := s.start(ctx, "List")
+	tasks, err := s.TaskRepository.List(ctx)
+	end(span, err)
+	return tasks, err
+}
+
+func (s *tracingStore) Get(ctx context.Context, id string) (Task, bool, error) {
+	ctx, span := s.start(ctx, "Get", taskIDAttr(id))
+	t, found, err := s.TaskRepository.Get(ctx, id)
+	end(span, err)
+	return t, found, err
+}
+
+func (s *tracingStore) Create(ctx context.Context, t Task) error {
+	ctx, span := s.start(ctx, "Create", taskIDAttr(t.ID))
+	err := s.TaskRepository.Create(ctx, t)
+	end(span, err)
+	return err
+}
+
+func (s *tracingStore) CreateMany(ctx context.Context, ts []Task) error {
+	ctx, span := s.start(ctx, "CreateMany", attribute.Int("task.count", len(ts)))
+	err := s.TaskRepository.CreateMany(ctx, ts)
+	end(span, err)
+	return err
+}
+
+func (s *tracingStore) Update(ctx context.Context, id string, t Task) (bool, error) {
+	ctx, span := s.start(ctx, "Update", taskIDAttr(id))
+	found, err := s.TaskRepository.Update(ctx, id, t)
+	end(span, err)
+	return found, err
+}
+
+func (s *tracingStore) Modify(ctx context.Context, id string, fn func(*Task) error) (Task, bool, error) {
+	ctx, span := s.start(ctx, "Modify", taskIDAttr(id))
+	t, found, err := s.TaskRepository.Modify(ctx, id, fn)
+	end(span, err)
+	return t, found, err
+}
+
+func (s *tracingStore) ModifyMany(ctx context.Context, ids []string, fn func(*Task) error) ([]Task, []string, error) {
+	ctx, span := s.start(ctx, "ModifyMany", attribute.Int("task.count", len(ids)))
+	modified, missing, err := s.TaskRepository.ModifyMany(ctx, ids, fn)
+	end(span, err)
+	return modified, missing, err
+}
+
+func (s *tracingStore) Delete(ctx context.Context, id string) (bool, error) {
+	ctx, span := s.start(ctx, "Delete", taskIDAttr(id))
+	found, err := s.TaskRepository.Delete(ctx, id)
+	end(span, err)
+	return found, err
+}
+
+func (s *tracingStore) TrashCompleted(ctx context.Context, at time.Time) ([]Task, error) {
+	ctx, span := s.start(ctx, "TrashCompleted")
+	trashed, err := s.TaskRepository.TrashCompleted(ctx, at)
+	end(span, err)
+	return trashed, err
+}
+
+func (s *tracingStore) ReplaceAll(ctx context.Context, ts []Task) ([]Task, error) {
+	ctx, span := s.start(ctx, "ReplaceAll", attribute.Int("task.count", len(ts)))
+	removed, err := s.TaskRepository.ReplaceAll(ctx, ts)
+	end(span, err)
+	return removed, err
+}
+
+func (s *tracingStore) Stats(ctx context.Context, now time.Time) (TaskStats, error) {
+	ctx, span := s.start(ctx, "Stats")
+	stats, err := s.TaskRepository.Stats(ctx, now)
+	end(span, err)
+	return stats, err
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Build metadata, injected at link time:
+//
+//	go build -ldflags "-X main.commit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	commit    = "unknown"
+	buildTime = "unknown"
+	version   = "dev"
+)
+
+// BuildInfo is the body of GET /version.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// buildInfo reports the injected metadata, falling back to the VCS details
+// the go command stamps into binaries built inside a git checkout.
+func buildInfo() BuildInfo {
+	info := BuildInfo{Version: version, Commit: commit, BuildTime: buildTime, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "unknown":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "unknown":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+	return info
+}
+
+func registerVersion(r gin.IRoutes) {
+	info := buildInfo()
+	r.GET("/version", func(c *gin.Context) {
+		c.JSON(200, info)
+	})
+}
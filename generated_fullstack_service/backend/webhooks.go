@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	webhookSignatureHeader = "X-Webhook-Signature"
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookTimeout         = 5 * time.Second
+	webhookAttempts        = 3
+	webhookWorkers         = 4
+	// webhookQueueSize bounds the deliveries waiting for a worker. Events
+	// arriving while it is full are dropped and logged.
+	webhookQueueSize = 1024
+)
+
+// webhookPayload is the JSON body POSTed to every webhook URL in the raw
+// event format.
+type webhookPayload struct {
+	Type      string    `json:"type"`
+	Task      Task      `json:"task"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type webhookDelivery struct {
+	url         string
+	body        []byte
+	contentType string
+	typ         string
+}
+
+// WebhookDispatcher POSTs task events to a fixed set of URLs from a pool of
+// workers, so slow receivers never hold up API requests.
+type WebhookDispatcher struct {
+	urls   []string
+	secret []byte
+	format string
+	client *http.Client
+	queue  chan webhookDelivery
+	// retryDelay is the wait before the first retry; it doubles after each
+	// failed attempt.
+	retryDelay time.Duration
+}
+
+// NewWebhookDispatcher delivers to urls, as a webhookPayload or, when format
+// is EventFormatCloudEvents, a CloudEvent in structured mode. When secret is
+// non-empty, each delivery carries an X-Webhook-Signature of "sha256="
+// followed by the hex HMAC-SHA256 of the body.
+func NewWebhookDispatcher(urls []string, secret, format string) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		urls:       urls,
+		secret:     []byte(secret),
+		format:     format,
+		client:     &http.Client{Timeout: webhookTimeout},
+		queue:      make(chan webhookDelivery, webhookQueueSize),
+		retryDelay: time.Second,
+	}
+}
+
+// Run subscribes to events and delivers them until ctx is done.
+func (d *WebhookDispatcher) Run(ctx context.Context, events *EventBroker) {
+	var wg sync.WaitGroup
+	for i := 0; i < webhookWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx)
+		}()
+	}
+	defer wg.Wait()
+
+	sub, unsubscribe := events.Subscribe()
+	defer func() { unsubscribe() }()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-sub:
+			if !ok {
+				if ctx.Err() != nil {
+					return
+				}
+				// The broker dropped us for falling behind; pick up again
+				// with the next event.
+				log.Println("webhooks: event subscription dropped, resubscribing")
+				sub, unsubscribe = events.Subscribe()
+				continue
+			}
+			d.enqueue(ev)
+		}
+	}
+}
+
+func (d *WebhookDispatcher) enqueue(ev TaskEvent) {
+	var payload any = webhookPayload{Type: ev.Type, Task: ev.Task, Timestamp: ev.At}
+	contentType := "application/json"
+	if d.format == EventFormatCloudEvents {
+		payload, contentType = newCloudEvent(ev), cloudEventsContentType
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("webhooks: encode %s event: %v", ev.Type, err)
+		return
+	}
+	for _, url := range d.urls {
+		select {
+		case d.queue <- webhookDelivery{url: url, body: body, contentType: contentType, typ: ev.Type}:
+		default:
+			log.Printf("webhooks: queue full, dropping %s event for task %s to %s", ev.Type, ev.Task.ID, url)
+		}
+	}
+}
+
+func (d *WebhookDispatcher) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case del := <-d.queue:
+			if err := d.deliver(ctx, del); err != nil {
+				log.Printf("webhooks: %s event to %s: %v", del.typ, del.url, err)
+			}
+		}
+	}
+}
+
+// deliver POSTs one event, retrying network errors, 429s and 5xx responses.
+func (d *WebhookDispatcher) deliver(ctx context.Context, del webhookDelivery) error {
+	delay := d.retryDelay
+	var err error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		var retry bool
+		if retry, err = d.post(ctx, del); err == nil || !retry {
+			return err
+		}
+		if attempt == webhookAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", webhookAttempts, err)
+}
+
+// post makes a single delivery attempt and reports whether a failure is
+// worth retrying.
+func (d *WebhookDispatcher) post(ctx context.Context, del webhookDelivery) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, del.url, bytes.NewReader(del.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", del.contentType)
+	req.Header.Set(webhookEventHeader, del.typ)
+	if len(d.secret) > 0 {
+		mac := hmac.New(sha256.New, d.secret)
+		mac.Write(del.body)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == 429 || resp.StatusCode >= 500:
+		return true, fmt.Errorf("receiver answered %s", resp.Status)
+	default:
+		return false, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is how long a connection may stay silent before it is
+	// considered dead; pings go out often enough to keep live ones open.
+	wsPongWait       = 60 * time.Second
+	wsPingInterval   = wsPongWait * 9 / 10
+	wsMaxMessageSize = 64 << 10
+)
+
+// Origins are checked against the CORS configuration in WebSocket, before
+// the upgrade.
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// wsCommand is a message sent by a client. Ref is echoed back in the reply so
+// clients can match replies to commands.
+type wsCommand struct {
+	Ref    string          `json:"ref,omitempty"`
+	Action string          `json:"action"`
+	ID     string          `json:"id,omitempty"`
+	Task   json.RawMessage `json:"task,omitempty"`
+}
+
+// wsReply answers a single wsCommand with either the affected task or an
+// error and the HTTP status the same request would have got. A create's
+// result carries its status too, 200 when dedup returned an existing task,
+// and under DUPLICATE_WARNING the id of an open task with the same title.
+type wsReply struct {
+	Type        string    `json:"type"`
+	Ref         string    `json:"ref,omitempty"`
+	Task        *Task     `json:"task,omitempty"`
+	Status      int       `json:"status,omitempty"`
+	DuplicateOf string    `json:"duplicate_of,omitempty"`
+	Error       *APIError `json:"error,omitempty"`
+}
+
+func wsError(ref string, status int, code, msg string) wsReply {
+	return wsReply{Type: "error", Ref: ref, Status: status, Error: &APIError{Code: code, Message: msg}}
+}
+
+// wsDecodeError reports a command's task that decodeJSON rejected, with the
+// status respondDecodeError gives the same body over REST.
+func wsDecodeError(ref string, err error) wsReply {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		status, code := modifyErrorStatus(err)
+		return wsError(ref, status, code, err.Error())
+	}
+	return wsError(ref, 400, CodeInvalidRequest, err.Error())
+}
+
+// wsClient serialises writes to a connection: gorilla allows only one
+// concurrent writer, and both the event pump and command replies write.
+type wsClient struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (w *wsClient) writeJSON(v any) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return w.conn.WriteJSON(v)
+}
+
+func (w *wsClient) ping() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+}
+
+// WebSocket pushes every task change to the client as a TaskEvent, or a
+// CloudEvent in that format, and runs
+// create, update and delete commands sent by it.
+func (h *TaskHandler) WebSocket(c *gin.Context) {
+	// CORSMiddleware has already decided whether this origin is allowed.
+	if c.GetHeader("Origin") != "" && c.Writer.Header().Get("Access-Control-Allow-Origin") == "" {
+		respondError(c, 403, CodeForbidden, "origin not allowed")
+		return
+	}
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written an error response.
+		return
+	}
+	defer conn.Close()
+	client := &wsClient{conn: conn}
+
+	events, unsubscribe := h.events.Subscribe()
+	defer unsubscribe()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(wsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case ev, ok := <-events:
+				// Closing the connection unblocks the read loop below.
+				if !ok || client.writeJSON(formatEvent(ev, h.eventFormat)) != nil {
+					conn.Close()
+					return
+				}
+			case <-ticker.C:
+				if client.ping() != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		if err := client.writeJSON(h.runCommand(c.Request.Context(), c.GetString(subjectKey), data)); err != nil {
+			return
+		}
+	}
+}
+
+// runCommand executes one client command on behalf of subject through the
+// same paths as the REST handlers, so the resulting change is broadcast and
+// audited like any other. Tasks in commands are decoded as strictly as
+// request bodies, and creates are deduplicated as POST /tasks is without
+// ?dedup.
+func (h *TaskHandler) runCommand(ctx context.Context, subject string, data []byte) wsReply {
+	var cmd wsCommand
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		return wsError("", 400, CodeInvalidRequest, "invalid command: "+err.Error())
+	}
+	// Commands arrive over a GET upgrade, which ReadOnly lets through.
+	if h.readOnly.Enabled() {
+		return wsError(cmd.Ref, 503, CodeReadOnly, "the server is in read-only mode; try again later")
+	}
+
+	switch cmd.Action {
+	case "create":
+		var task Task
+		if err := decodeJSON(bytes.NewReader(cmd.Task), &task); err != nil {
+			return wsDecodeError(cmd.Ref, err)
+		}
+		applyDefaults(&task)
+		if err := validateTask(task); err != nil {
+			status, code := modifyErrorStatus(err)
+			return wsError(cmd.Ref, status, code, err.Error())
+		}
+		created, existing, duplicateOf, err := h.createDeduped(ctx, subject, task, h.dedup, func() (Task, error) {
+			return h.createTask(ctx, subject, task)
+		})
+		if err != nil {
+			status, code := modifyErrorStatus(err)
+			return wsError(cmd.Ref, status, code, err.Error())
+		}
+		status := 201
+		if existing {
+			status = 200
+		}
+		return wsReply{Type: "result", Ref: cmd.Ref, Task: &created, Status: status, DuplicateOf: duplicateOf}
+
+	case "update":
+		var patch TaskPatch
+		if err := decodeJSON(bytes.NewReader(cmd.Task), &patch); err != nil {
+			return wsDecodeError(cmd.Ref, err)
+		}
+		task, found, err := h.patchTask(ctx, subject, cmd.ID, patch, "")
+		if err != nil {
+			status, code := modifyErrorStatus(err)
+			return wsError(cmd.Ref, status, code, err.Error())
+		}
+		if !found {
+			return wsError(cmd.Ref, 404, CodeTaskNotFound, "task not found")
+		}
+		return wsReply{Type: "result", Ref: cmd.Ref, Task: &task}
+
+	case "delete":
+		found, err := h.trashTask(ctx, subject, cmd.ID)
+		if err != nil {
+			status, code := modifyErrorStatus(err)
+			return wsError(cmd.Ref, status, code, err.Error())
+		}
+		if !found {
+			return wsError(cmd.Ref, 404, CodeTaskNotFound, "task not found")
+		}
+		return wsReply{Type: "result", Ref: cmd.Ref}
+	}
+	return wsError(cmd.Ref, 400, CodeInvalidRequest, "action must be one of create, update, delete")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialWS opens a WebSocket to the test server's /v1/ws.
+func dialWS(t *testing.T, cfg Config) *websocket.Conn {
+	t.Helper()
+	srv := newTestServer(t, cfg)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// wsCall sends cmd and returns its reply, skipping the change events sent
+// in between.
+func wsCall(t *testing.T, conn *websocket.Conn, cmd string) wsReply {
+	t.Helper()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(cmd)); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reply wsReply
+		if err := json.Unmarshal(data, &reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Type == "result" || reply.Type == "error" {
+			return reply
+		}
+	}
+}
+
+func TestWebSocketCreateRejectsUnknownFields(t *testing.T) {
+	conn := dialWS(t, testConfig())
+	reply := wsCall(t, conn, `{"ref":"1","action":"create","task":{"title":"Typo","dne":true}}`)
+	if reply.Type != "error" || reply.Status != 400 || reply.Error.Code != CodeInvalidRequest {
+		t.Fatalf("reply = %+v, want a 400 %s error", reply, CodeInvalidRequest)
+	}
+	if !strings.Contains(reply.Error.Message, `"dne"`) {
+		t.Errorf("message %q doesn't name the unknown field", reply.Error.Message)
+	}
+
+	reply = wsCall(t, conn, `{"ref":"2","action":"create","task":{"title":"Fine"}}`)
+	if reply.Type != "result" || reply.Status != 201 || reply.Task == nil || reply.Task.Title != "Fine" {
+		t.Fatalf("reply = %+v, want a 201 result", reply)
+	}
+	reply = wsCall(t, conn, `{"ref":"3","action":"update","id":"`+reply.Task.ID+`","task":{"dne":true}}`)
+	if reply.Type != "error" || reply.Status != 400 {
+		t.Errorf("update with an unknown field: reply = %+v, want a 400 error", reply)
+	}
+}
+
+func TestWebSocketCreateDedup(t *testing.T) {
+	cfg := testConfig()
+	cfg.Dedup = true
+	conn := dialWS(t, cfg)
+
+	first := wsCall(t, conn, `{"action":"create","task":{"title":"Buy milk"}}`)
+	again := wsCall(t, conn, `{"action":"create","task":{"title":"  buy MILK "}}`)
+	if first.Task == nil || again.Task == nil {
+		t.Fatalf("replies = %+v, %+v, want two results", first, again)
+	}
+	if again.Status != 200 || again.Task.ID != first.Task.ID {
+		t.Errorf("second create: status %d, id %s; want 200 and the first task %s", again.Status, again.Task.ID, first.Task.ID)
+	}
+}
+
+func TestWebSocketCreateDuplicateWarning(t *testing.T) {
+	cfg := testConfig()
+	cfg.DuplicateWarning = true
+	conn := dialWS(t, cfg)
+
+	first := wsCall(t, conn, `{"action":"create","task":{"title":"Buy milk"}}`)
+	again := wsCall(t, conn, `{"action":"create","task":{"title":"Buy milk"}}`)
+	if first.Task == nil || again.Task == nil {
+		t.Fatalf("replies = %+v, %+v, want two results", first, again)
+	}
+	if again.Status != 201 || again.Task.ID == first.Task.ID || again.DuplicateOf != first.Task.ID {
+		t.Errorf("second create: status %d, id %s, duplicate_of %q; want 201, a new task and %s",
+			again.Status, again.Task.ID, again.DuplicateOf, first.Task.ID)
+	}
+	if first.DuplicateOf != "" {
+		t.Errorf("first create: duplicate_of %q, want none", first.DuplicateOf)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/xml"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// taskListXML gives a list of tasks the single root element XML requires.
+type taskListXML struct {
+	XMLName xml.Name `xml:"tasks"`
+	Tasks   []Task   `xml:"task"`
+}
+
+// wantsXML reports whether the client's Accept header prefers XML. Anything
+// else, including values we can't serve, gets JSON rather than a 406.
+func wantsXML(c *gin.Context) bool {
+	switch c.NegotiateFormat(binding.MIMEJSON, binding.MIMEXML, binding.MIMEXML2) {
+	case binding.MIMEXML, binding.MIMEXML2:
+		return true
+	}
+	return false
+}
+
+// xmlBody returns the value to encode in place of v in an XML response.
+func xmlBody(v any) any {
+	if tasks, ok := v.([]Task); ok {
+		return taskListXML{Tasks: tasks}
+	}
+	return v
+}